@@ -1,5 +1,12 @@
 package imports
 
 import (
+	_ "github.com/go-logr/logr"
+	_ "github.com/go-logr/logr/funcr"
+	_ "github.com/rs/zerolog"
+	_ "github.com/sirupsen/logrus"
 	_ "github.com/stretchr/testify/require"
+	_ "go.uber.org/zap"
+	_ "go.uber.org/zap/zapcore"
+	_ "golang.org/x/xerrors"
 )
@@ -0,0 +1,52 @@
+//go:build go1.20
+
+package errors_test
+
+import (
+	stderrors "errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maratori/errors"
+)
+
+func TestStdJoin(t *testing.T) {
+	t.Parallel()
+
+	t.Run("errors.Join is flattened", func(t *testing.T) {
+		t.Parallel()
+		original1 := errors.New("new err 1").WithField("key1", "value1").E()
+		original2 := errors.New("new err 2").WithField("key2", "value2").E()
+		err := errors.Wrap("prefix", stderrors.Join(original1, original2)).E()
+		require.EqualError(t, err, "prefix: new err 1\nnew err 2")
+
+		errs := errors.Errors(err)
+		require.Len(t, errs, 2)
+		require.EqualError(t, errs[0], "prefix: new err 1")
+		require.EqualError(t, errs[1], "prefix: new err 2")
+		require.Equal(t, errors.Fields{"key1": "value1"}, errors.FieldsFromError(errs[0]))
+		require.Equal(t, errors.Fields{"key2": "value2"}, errors.FieldsFromError(errs[1]))
+	})
+
+	t.Run("fmt.Errorf with several %w is flattened", func(t *testing.T) {
+		t.Parallel()
+		original1 := errors.New("new err 1").WithField("key1", "value1").E()
+		original2 := stderrors.New("new err 2")
+		err := fmt.Errorf("%w, %w", original1, original2)
+
+		errs := errors.Errors(err)
+		require.Len(t, errs, 2)
+		require.EqualError(t, errs[0], "new err 1")
+		require.EqualError(t, errs[1], "new err 2")
+		require.Equal(t, errors.Fields{"key1": "value1"}, errors.FieldsFromError(err))
+	})
+
+	t.Run("IsStructured checks each error", func(t *testing.T) {
+		t.Parallel()
+		std1, std2 := stderrors.New("new err 1"), stderrors.New("new err 2")
+		require.False(t, errors.IsStructured(stderrors.Join(std1, std2)))
+		require.True(t, errors.IsStructured(stderrors.Join(std1, errors.Err(std2).E())))
+	})
+}
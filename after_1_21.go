@@ -0,0 +1,105 @@
+//go:build go1.21
+
+package errors
+
+import (
+	"log/slog"
+	"strconv"
+)
+
+//nolint:exhaustruct // false positive
+var (
+	_ slog.LogValuer = wrapper{}
+	_ slog.LogValuer = withPrefix{}
+	_ slog.LogValuer = withFields{}
+	_ slog.LogValuer = withStack{}
+	_ slog.LogValuer = many{}
+)
+
+func (e wrapper) LogValue() slog.Value    { return logValue(e) }
+func (e withPrefix) LogValue() slog.Value { return logValue(e) }
+func (e withFields) LogValue() slog.Value { return logValue(e) }
+func (e withStack) LogValue() slog.Value  { return logValue(e) }
+func (e many) LogValue() slog.Value       { return logValue(e) }
+
+// logValue renders a single leaf as a group with message and fields.
+// Several leaves are rendered as a group with message and one sub-group per leaf.
+// Fields named "message" or "leaves" are renamed with FieldList.RenameReserved.
+func logValue(err treeNode) slog.Value {
+	errs := err.Errors(newOptions(nil))
+	if len(errs) == 1 {
+		return leafLogValue(errs[0])
+	}
+	leaves := make([]slog.Attr, 0, len(errs))
+	for i, leaf := range errs {
+		leaves = append(leaves, slog.Attr{
+			Key:   strconv.Itoa(i),
+			Value: leafLogValue(leaf),
+		})
+	}
+	return slog.GroupValue(
+		slog.String(messageKey, err.Error()),
+		slog.Attr{
+			Key:   leavesKey,
+			Value: slog.GroupValue(leaves...),
+		},
+	)
+}
+
+func leafLogValue(leaf errorWithFields) slog.Value {
+	attrs := make([]slog.Attr, 0, len(leaf.fields)+1)
+	attrs = append(attrs, slog.String(messageKey, leaf.Error()))
+	return slog.GroupValue(appendAttrs(attrs, NormalizeFields(leaf.fields).RenameReserved(messageKey, leavesKey))...)
+}
+
+// appendAttrs renders groups of fields as [slog.Group].
+func appendAttrs(attrs []slog.Attr, fields FieldList) []slog.Attr {
+	for _, f := range fields {
+		if group, ok := f.Value.(FieldList); ok {
+			attrs = append(attrs, slog.Attr{
+				Key:   f.Key,
+				Value: slog.GroupValue(appendAttrs(make([]slog.Attr, 0, len(group)), group)...),
+			})
+			continue
+		}
+		attrs = append(attrs, slog.Any(f.Key, f.Value))
+	}
+	return attrs
+}
+
+// attrFields converts [slog.Attr] passed to ErrorBuilder.With into fields, a group is converted with Group.
+// Empty attributes and groups are ignored and groups with empty key are inlined, the same as slog handlers do.
+func attrFields(arg any) ([]Field, bool) {
+	attr, ok := arg.(slog.Attr)
+	if !ok {
+		return nil, false
+	}
+	return appendAttrFields(nil, attr), true
+}
+
+func appendAttrFields(fields []Field, attr slog.Attr) []Field {
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+	if attr.Value.Kind() != slog.KindGroup {
+		return append(fields, Field{
+			Key:   attr.Key,
+			Value: attr.Value.Any(),
+		})
+	}
+	attrs := attr.Value.Group()
+	if attr.Key == "" {
+		for _, a := range attrs {
+			fields = appendAttrFields(fields, a)
+		}
+		return fields
+	}
+	group := make([]Field, 0, len(attrs))
+	for _, a := range attrs {
+		group = appendAttrFields(group, a)
+	}
+	if len(group) == 0 {
+		return fields
+	}
+	return append(fields, Group(attr.Key, group...))
+}
@@ -0,0 +1,112 @@
+//go:build go1.21
+
+package errors_test
+
+import (
+	"bytes"
+	stderrors "errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maratori/errors"
+)
+
+func TestLogValue(t *testing.T) {
+	t.Parallel()
+
+	t.Run("error without fields", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").E()
+		require.JSONEq(t, `{"msg":"failed","err":{"message":"new err"}}`, logJSON(err))
+	})
+
+	t.Run("wrapped error with fields", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithField("key2", 2).Wrap("prefix").WithField("key1", "value1").E()
+		require.JSONEq(t,
+			`{"msg":"failed","err":{"message":"prefix: new err","key1":"value1","key2":2}}`,
+			logJSON(err),
+		)
+	})
+
+	t.Run("foreign error with fields", func(t *testing.T) {
+		t.Parallel()
+		err := errors.WithField(stderrors.New("new err"), "key", "value").E()
+		require.JSONEq(t, `{"msg":"failed","err":{"message":"new err","key":"value"}}`, logJSON(err))
+	})
+
+	t.Run("groups", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithGroup("db", errors.Field{Key: "table", Value: "orders"}).E()
+		require.JSONEq(t, `{"msg":"failed","err":{"message":"new err","db":{"table":"orders"}}}`, logJSON(err))
+	})
+
+	t.Run("reserved keys", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").With("message", "field", "leaves", 1).E()
+		require.Equal(t,
+			`{"msg":"failed","err":{"message":"new err","message#1":"field","leaves#1":1}}`+"\n",
+			logJSON(err),
+		)
+	})
+
+	t.Run("joined errors", func(t *testing.T) {
+		t.Parallel()
+		err := errors.Wrap("prefix", errors.Join(
+			errors.New("new err 1").WithField("key1", "value1").E(),
+			errors.New("new err 2").WithField("key2", "value2").E(),
+		)).WithField("key3", "value3").E()
+		require.JSONEq(t, `{"msg":"failed","err":{
+			"message":"prefix: new err 1\nnew err 2",
+			"leaves":{
+				"0":{"message":"prefix: new err 1","key1":"value1","key3":"value3"},
+				"1":{"message":"prefix: new err 2","key2":"value2","key3":"value3"}
+			}
+		}}`, logJSON(err))
+	})
+}
+
+func logJSON(err error) string {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		AddSource: false,
+		Level:     nil,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey) {
+				return slog.Attr{} //nolint:exhaustruct // empty attr is dropped by handler
+			}
+			return a
+		},
+	}))
+	logger.Error("failed", "err", err)
+	return buf.String()
+}
+
+func TestWithAttr(t *testing.T) {
+	t.Parallel()
+
+	t.Run("attributes", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").With(slog.Int("key1", 1), "key2", 2, slog.String("key3", "value3")).E()
+		require.Equal(t, errors.FieldList{
+			{Key: "key1", Value: int64(1)},
+			{Key: "key2", Value: 2},
+			{Key: "key3", Value: "value3"},
+		}, errors.OrderedFields(err))
+	})
+
+	t.Run("groups", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").With(
+			slog.Group("db", "table", "orders", slog.Group("empty")),
+			slog.Group("", "key", 1),
+			slog.Attr{},
+		).E()
+		require.Equal(t, errors.FieldList{
+			{Key: "db", Value: errors.FieldList{{Key: "table", Value: "orders"}}},
+			{Key: "key", Value: int64(1)},
+		}, errors.OrderedFields(err))
+	})
+}
@@ -0,0 +1,8 @@
+//go:build !go1.21
+
+package errors
+
+// attrFields never converts anything, because slog.Attr is available since go1.21.
+func attrFields(any) ([]Field, bool) {
+	return nil, false
+}
@@ -0,0 +1,129 @@
+package errors
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+// ConflictPolicy defines how fields are merged if an outer error sets a key already set by an inner error
+// along one path of the errors tree. Groups (see Group) are merged key by key with the same policy.
+type ConflictPolicy int32
+
+const (
+	// InnerWins keeps the value of the inner error. It's the default policy.
+	InnerWins ConflictPolicy = iota
+	// OuterWins replaces the value of the inner error with the value of the outer one, position is kept.
+	OuterWins
+	// KeepBoth keeps the value of the inner error and adds the value of the outer one
+	// with the first free key of the form "key#N", e.g. "key#1".
+	KeepBoth
+	// PanicOnConflict panics on extraction of fields. It's intended to catch duplicated keys in tests.
+	PanicOnConflict
+)
+
+//nolint:gochecknoglobals // global switch is the way to set the policy for the whole application
+var globalConflictPolicy int32
+
+// SetConflictPolicy sets the policy used by extraction functions if WithConflictPolicy isn't passed.
+// It panics if policy is unknown.
+func SetConflictPolicy(policy ConflictPolicy) {
+	if !policy.valid() {
+		panic("misuse of errors.SetConflictPolicy: unknown conflict policy")
+	}
+	atomic.StoreInt32(&globalConflictPolicy, int32(policy))
+}
+
+func conflictPolicy() ConflictPolicy {
+	return ConflictPolicy(atomic.LoadInt32(&globalConflictPolicy))
+}
+
+func (p ConflictPolicy) valid() bool {
+	return p >= InnerWins && p <= PanicOnConflict
+}
+
+// Option configures extraction of fields, e.g. in Errors or FieldsFromError.
+type Option func(*options)
+
+type options struct {
+	policy     ConflictPolicy
+	provenance bool // see Provenance
+}
+
+// WithConflictPolicy overrides the policy set by SetConflictPolicy for a single call.
+// It panics if policy is unknown.
+func WithConflictPolicy(policy ConflictPolicy) Option {
+	if !policy.valid() {
+		panic("misuse of errors.WithConflictPolicy: unknown conflict policy")
+	}
+	return func(o *options) {
+		o.policy = policy
+	}
+}
+
+func newOptions(opts []Option) options {
+	res := options{
+		policy:     conflictPolicy(),
+		provenance: false,
+	}
+	for _, opt := range opts {
+		opt(&res)
+	}
+	return res
+}
+
+// leavesOf returns leaves of the errors tree with fields merged according to opts.
+func leavesOf(err error, opts []Option) []errorWithFields {
+	return wrapper{err: err}.Errors(newOptions(opts))
+}
+
+// joinFields puts inner fields first, because they are added earlier.
+// Duplicated keys are resolved according to policy, but groups are always merged key by key.
+func joinFields(outer FieldList, inner FieldList, policy ConflictPolicy) FieldList {
+	switch {
+	case len(outer) == 0:
+		return inner
+	case len(inner) == 0:
+		return outer
+	}
+	res := make(FieldList, 0, len(outer)+len(inner))
+	res = append(res, inner...)
+	for _, f := range outer {
+		i := res.index(f.Key)
+		if i < 0 {
+			res = append(res, f)
+			continue
+		}
+		innerGroup, innerOK := res[i].Value.(FieldList)
+		outerGroup, outerOK := f.Value.(FieldList)
+		if innerOK && outerOK {
+			res[i].Value = joinFields(outerGroup, innerGroup, policy)
+			continue
+		}
+		switch policy {
+		case InnerWins:
+			// Inner value is kept.
+		case OuterWins:
+			res[i].Value = f.Value
+		case KeepBoth:
+			res = append(res, Field{
+				Key:   res.freeKey(f.Key, outer),
+				Value: f.Value,
+			})
+		case PanicOnConflict:
+			panic(fmt.Sprintf("errors: field %q is set twice", f.Key))
+		}
+	}
+	return res
+}
+
+// freeKey returns the first key of the form "key#N" not present in the list and in other,
+// so it doesn't collide with a key added from other later.
+func (l FieldList) freeKey(key string, other FieldList) string {
+	for n := 1; ; n++ {
+		res := key + "#" + strconv.Itoa(n)
+		if l.index(res) < 0 && other.index(res) < 0 {
+			return res
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package errors_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maratori/errors"
+)
+
+func TestWithConflictPolicy(t *testing.T) {
+	t.Parallel()
+
+	err := errors.New("new err").
+		WithField("key1", 1).
+		WithGroup("db", errors.Field{Key: "table", Value: "orders"}).
+		Wrap("prefix").
+		With(
+			errors.Field{Key: "key1", Value: 2},
+			errors.Field{Key: "key2", Value: 2},
+			errors.Group("db", errors.Field{Key: "table", Value: "users"}, errors.Field{Key: "op", Value: "select"}),
+		).
+		WithField("key1", 3).
+		E()
+
+	tests := []struct {
+		name     string
+		policy   errors.ConflictPolicy
+		expected errors.FieldList
+	}{
+		{
+			name:   "inner wins",
+			policy: errors.InnerWins,
+			expected: errors.FieldList{
+				{Key: "key1", Value: 1},
+				{Key: "db", Value: errors.FieldList{{Key: "table", Value: "orders"}, {Key: "op", Value: "select"}}},
+				{Key: "key2", Value: 2},
+			},
+		},
+		{
+			name:   "outer wins",
+			policy: errors.OuterWins,
+			expected: errors.FieldList{
+				{Key: "key1", Value: 3},
+				{Key: "db", Value: errors.FieldList{{Key: "table", Value: "users"}, {Key: "op", Value: "select"}}},
+				{Key: "key2", Value: 2},
+			},
+		},
+		{
+			name:   "keep both",
+			policy: errors.KeepBoth,
+			expected: errors.FieldList{
+				{Key: "key1", Value: 1},
+				{Key: "db", Value: errors.FieldList{
+					{Key: "table", Value: "orders"},
+					{Key: "table#1", Value: "users"},
+					{Key: "op", Value: "select"},
+				}},
+				{Key: "key1#1", Value: 2},
+				{Key: "key2", Value: 2},
+				{Key: "key1#2", Value: 3},
+			},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			opt := errors.WithConflictPolicy(tc.policy)
+			require.Equal(t, tc.expected, errors.OrderedFields(err, opt))
+			require.Equal(t, tc.expected.Map(), errors.FieldsFromError(err, opt))
+			require.Equal(t, []errors.FieldList{tc.expected}, leafFieldLists(errors.Leaves(err, opt)))
+		})
+	}
+}
+
+func TestKeepBothKeyCollision(t *testing.T) {
+	t.Parallel()
+	err := errors.New("new err").WithField("a", 1).Wrap("prefix").With("a", 2, "a#1", 3).E()
+	require.Equal(t, errors.FieldList{
+		{Key: "a", Value: 1},
+		{Key: "a#2", Value: 2},
+		{Key: "a#1", Value: 3},
+	}, errors.OrderedFields(err, errors.WithConflictPolicy(errors.KeepBoth)))
+}
+
+func TestPanicOnConflict(t *testing.T) {
+	t.Parallel()
+
+	opt := errors.WithConflictPolicy(errors.PanicOnConflict)
+
+	t.Run("key is set twice along one path", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithField("key", 1).Wrap("prefix").WithField("key", 2).E()
+		require.PanicsWithValue(t, `errors: field "key" is set twice`, func() {
+			errors.FieldsFromError(err, opt)
+		})
+	})
+
+	t.Run("key is set twice in group", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").
+			WithGroup("db", errors.Field{Key: "table", Value: "orders"}).
+			WithGroup("db", errors.Field{Key: "table", Value: "users"}).
+			E()
+		require.PanicsWithValue(t, `errors: field "table" is set twice`, func() {
+			errors.Errors(err, opt)
+		})
+	})
+
+	t.Run("groups are merged", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").
+			WithGroup("db", errors.Field{Key: "table", Value: "orders"}).
+			WithGroup("db", errors.Field{Key: "op", Value: "select"}).
+			E()
+		require.Equal(t, errors.FieldList{
+			{Key: "db", Value: errors.FieldList{{Key: "table", Value: "orders"}, {Key: "op", Value: "select"}}},
+		}, errors.OrderedFields(err, opt))
+	})
+
+	t.Run("key is set in different leaves", func(t *testing.T) {
+		t.Parallel()
+		err := errors.Join(
+			errors.New("err1").WithField("key", 1).E(),
+			errors.New("err2").WithField("key", 2).E(),
+		)
+		require.Equal(t, []errors.Fields{{"key": 1}, {"key": 2}}, errors.LeafFields(err, opt))
+	})
+}
+
+func TestUnknownConflictPolicy(t *testing.T) {
+	t.Parallel()
+
+	require.PanicsWithValue(t, "misuse of errors.WithConflictPolicy: unknown conflict policy", func() {
+		errors.WithConflictPolicy(errors.ConflictPolicy(-1))
+	})
+	require.PanicsWithValue(t, "misuse of errors.SetConflictPolicy: unknown conflict policy", func() {
+		errors.SetConflictPolicy(errors.PanicOnConflict + 1)
+	})
+}
+
+//nolint:paralleltest // modifies global policy
+func TestSetConflictPolicy(t *testing.T) {
+	errors.SetConflictPolicy(errors.OuterWins)
+	t.Cleanup(func() {
+		errors.SetConflictPolicy(errors.InnerWins)
+	})
+
+	err := errors.New("new err").WithField("key", 1).Wrap("prefix").WithField("key", 2).E()
+
+	t.Run("global policy", func(t *testing.T) {
+		require.Equal(t, errors.Fields{"key": 2}, errors.FieldsFromError(err))
+		require.Contains(t, fmt.Sprintf("%+v", err), "fields: key=2")
+	})
+
+	t.Run("option overrides global policy", func(t *testing.T) {
+		opt := errors.WithConflictPolicy(errors.InnerWins)
+		require.Equal(t, errors.Fields{"key": 1}, errors.FieldsFromError(err, opt))
+	})
+}
+
+func leafFieldLists(leaves []errors.Leaf) []errors.FieldList {
+	res := make([]errors.FieldList, 0, len(leaves))
+	for _, leaf := range leaves {
+		res = append(res, leaf.Fields)
+	}
+	return res
+}
@@ -4,6 +4,7 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 func Unwrap(err error) error {
@@ -20,8 +21,10 @@ func As(err error, target any) bool {
 
 type Fields = map[string]any
 
-func Errors(err error) []error {
-	errs := wrapper{err: err}.Errors()
+// Errors returns leaves of the errors tree, each leaf has message with all prefixes and fields of its path.
+// Fields are merged according to the conflict policy, see SetConflictPolicy and WithConflictPolicy.
+func Errors(err error, opts ...Option) []error {
+	errs := leavesOf(err, opts)
 	res := make([]error, 0, len(errs))
 	for _, e := range errs {
 		res = append(res, e)
@@ -29,38 +32,75 @@ func Errors(err error) []error {
 	return res
 }
 
-func FieldsFromError(err error) Fields {
-	errs := wrapper{err: err}.Errors()
-	if len(errs) == 0 || errs[0].fields == nil {
+// FieldsFromError returns fields of the first leaf of the errors tree (see Errors).
+// Fields of other leaves are ignored, use MergedFields, CommonFields or LeafFields to get them.
+func FieldsFromError(err error, opts ...Option) Fields {
+	errs := leavesOf(err, opts)
+	if len(errs) == 0 {
 		// It may be handy to update map returned by FieldsFromError.
 		// Need to return empty map instead of nil.
 		return Fields{}
 	}
-	return clone(errs[0].fields)
+	return errs[0].fields.Map()
 }
 
+// RangeFields calls f for each field returned by FieldsFromError in order of OrderedFields
+// without copying them. If f returns false, RangeFields stops the iteration.
+func RangeFields(err error, f func(key string, value any) bool, opts ...Option) {
+	errs := leavesOf(err, opts)
+	if len(errs) == 0 {
+		return
+	}
+	for _, field := range errs[0].fields {
+		if !f(field.Key, field.Value) {
+			return
+		}
+	}
+}
+
+// ErrorBuilder is an immutable builder of an error. Each method returns a new builder and never changes
+// the receiver, so several errors may be derived from a shared base builder, including concurrently:
+//
+//	base := errors.New("can't process order").WithField("order_id", orderID)
+//	err1 := base.WithField("step", "pay").E()  // doesn't have "step" = "ship"
+//	err2 := base.WithField("step", "ship").E() // doesn't have "step" = "pay"
 type ErrorBuilder struct {
-	err treeNode
+	err      treeNode
+	snapshot int64 // see WithSnapshot, 0 means the global setting, negative value disables snapshots
 }
 
 func New(msg string) *ErrorBuilder {
-	return Err(errors.New(msg))
+	return build(errors.New(msg), 1)
 }
 
 func Err(err error) *ErrorBuilder {
+	return build(err, 1)
+}
+
+// build is called directly by exported functions, skip is the number of frames between build and user code.
+func build(err error, skip int) *ErrorBuilder {
 	// Type switch instead of errors.As() because we don't want to extract wrapped error to not miss wrapper.
 	switch e := err.(type) { //nolint:errorlint // see comment above
 	case nil:
 		return nil
 	case treeNode:
 		return &ErrorBuilder{
-			err: e,
+			err:      e,
+			snapshot: 0,
 		}
 	default:
+		var node treeNode = wrapper{
+			err: err,
+		}
+		if stacksEnabled() {
+			node = withStack{
+				err:   node,
+				stack: callers(skip),
+			}
+		}
 		return &ErrorBuilder{
-			err: wrapper{
-				err: err,
-			},
+			err:      node,
+			snapshot: 0,
 		}
 	}
 }
@@ -76,40 +116,105 @@ func (e *ErrorBuilder) Wrap(prefix string) *ErrorBuilder {
 	if e == nil {
 		return nil
 	}
-	e.err = withPrefix{
-		err:    e.err,
-		prefix: prefix,
+	return &ErrorBuilder{
+		err: withPrefix{
+			err:    e.err,
+			prefix: prefix,
+		},
+		snapshot: e.snapshot,
 	}
-	return e
 }
 
 func (e *ErrorBuilder) WithFields(fields Fields) *ErrorBuilder {
+	return e.withFields(fieldsFromMap(fields), 1)
+}
+
+func (e *ErrorBuilder) WithField(key string, value any) *ErrorBuilder {
+	return e.withFields(FieldList{{Key: key, Value: value}}, 1)
+}
+
+// With adds fields the same way as WithFields does, but keeps their order.
+// Arguments follow conventions of [slog.Logger.With]: each argument is either a string key or a Key followed by
+// a value, a Field or [slog.Attr] (go1.21+). A value without a key is added with "!BADKEY" key, e.g.
+//
+//	errors.New("can't find order").With("user_id", userID, OrderID, orderID)
+func (e *ErrorBuilder) With(args ...any) *ErrorBuilder {
+	return e.withFields(argsToFields(args), 1)
+}
+
+// WithGroup adds a group of fields, see Group.
+func (e *ErrorBuilder) WithGroup(name string, fields ...Field) *ErrorBuilder {
+	return e.withFields(FieldList{Group(name, fields...)}, 1)
+}
+
+// withFields is called directly by exported methods, skip is the number of frames between withFields and user code.
+// Call site is captured for Provenance only if stacks are enabled.
+func (e *ErrorBuilder) withFields(fields FieldList, skip int) *ErrorBuilder {
 	if e == nil {
 		return nil
 	}
-	e.err = withFields{
-		err:    e.err,
-		fields: clone(fields),
+	var site Stack
+	if stacksEnabled() {
+		site = caller(skip)
+	}
+	return &ErrorBuilder{
+		err: withFields{
+			err:    e.err,
+			fields: snapshotFields(fields, e.snapshot),
+			caller: site,
+		},
+		snapshot: e.snapshot,
 	}
-	return e
 }
 
-func (e *ErrorBuilder) WithField(key string, value any) *ErrorBuilder {
-	return e.WithFields(Fields{
-		key: value,
-	})
+// WithStack captures stack of the caller for leaves that don't have a stack yet.
+// It works regardless of CaptureStacks.
+func (e *ErrorBuilder) WithStack() *ErrorBuilder {
+	if e == nil {
+		return nil
+	}
+	return &ErrorBuilder{
+		err: withStack{
+			err:   e.err,
+			stack: callers(0),
+		},
+		snapshot: e.snapshot,
+	}
+}
+
+// WithSnapshot overrides SnapshotFields for fields added later by the returned builder and builders derived from it.
+// It works regardless of SnapshotFields, budget <= 0 disables snapshots.
+//
+//	errors.New("can't save order").WithSnapshot(100).WithField("order", order)
+func (e *ErrorBuilder) WithSnapshot(budget int) *ErrorBuilder {
+	if e == nil {
+		return nil
+	}
+	snapshot := int64(budget)
+	if snapshot <= 0 {
+		snapshot = -1
+	}
+	return &ErrorBuilder{
+		err:      e.err,
+		snapshot: snapshot,
+	}
 }
 
 func Wrap(prefix string, err error) *ErrorBuilder {
-	return Err(err).Wrap(prefix)
+	return build(err, 1).Wrap(prefix)
 }
 
 func WithFields(err error, fields Fields) *ErrorBuilder {
-	return Err(err).WithFields(fields)
+	return build(err, 1).withFields(fieldsFromMap(fields), 1)
 }
 
 func WithField(err error, key string, value any) *ErrorBuilder {
-	return Err(err).WithField(key, value)
+	return build(err, 1).withFields(FieldList{{Key: key, Value: value}}, 1)
+}
+
+// With is shorthand for Err(err).With(args...), see ErrorBuilder.With.
+func With(err error, args ...any) *ErrorBuilder {
+	return build(err, 1).withFields(argsToFields(args), 1)
 }
 
 func Join(errs ...error) error {
@@ -150,26 +255,13 @@ func AppendInto(into *error, err error) {
 	*into = Join(*into, err)
 }
 
-func joinFields(outer Fields, inner Fields) Fields {
-	switch {
-	case len(outer) == 0:
-		return inner
-	case len(inner) == 0:
-		return outer
-	}
-	res := make(Fields, len(outer)+len(inner))
-	for k, v := range outer {
-		res[k] = v
-	}
-	for k, v := range inner {
-		res[k] = v // inner map has higher priority for duplicated fields
-	}
-	return res
-}
-
 type errorWithFields struct {
-	err    error
-	fields Fields
+	err     error
+	fields  FieldList
+	stack   *Stack
+	cause   error         // original leaf error without prefixes
+	path    []string      // prefixes from the outermost to the innermost
+	origins []fieldOrigin // fields set by each wrapper from the innermost, collected only for Provenance
 }
 
 func (e errorWithFields) Error() string {
@@ -183,7 +275,7 @@ func (e errorWithFields) Unwrap() error {
 type treeNode interface {
 	isMyError()
 	error
-	Errors() []errorWithFields
+	Errors(o options) []errorWithFields
 	// Optional methods:
 	//   Unwrap() error
 	//   Unwrap() []error
@@ -194,6 +286,7 @@ var (
 	_ treeNode = wrapper{}
 	_ treeNode = withPrefix{}
 	_ treeNode = withFields{}
+	_ treeNode = withStack{}
 	_ treeNode = many{}
 )
 
@@ -201,25 +294,42 @@ func (e wrapper) isMyError() {}
 
 func (e withPrefix) isMyError() {}
 func (e withFields) isMyError() {}
+func (e withStack) isMyError()  {}
 func (e many) isMyError()       {}
 
 type wrapper struct {
 	err error
 }
 
-func (e wrapper) Errors() []errorWithFields {
+func (e wrapper) Errors(o options) []errorWithFields {
 	// Type switch instead of errors.As() because we don't want to extract wrapped error to not miss wrapper.
 	switch err := e.err.(type) { //nolint:errorlint // see comment above
 	case nil:
 		return nil
 	case treeNode:
-		return err.Errors()
+		return err.Errors(o)
 	case errorWithFields:
-		return []errorWithFields{err}
+		return []errorWithFields{o.withOrigin(err)}
+	case Leaf:
+		return []errorWithFields{o.withOrigin(err.errorWithFields())}
+	case *Leaf:
+		return []errorWithFields{o.withOrigin(err.errorWithFields())}
 	default:
+		if errs, ok := foreignErrors(err); ok {
+			if res := flattenForeign(errs, o); len(res) > 0 {
+				return res
+			}
+		}
+		if inner := errors.Unwrap(err); inner != nil && needsUnwrap(inner) {
+			return unwrapForeign(err, inner, o)
+		}
 		return []errorWithFields{{
-			err:    err,
-			fields: nil,
+			err:     err,
+			fields:  nil,
+			stack:   nil,
+			cause:   err,
+			path:    nil,
+			origins: nil,
 		}}
 	}
 }
@@ -237,16 +347,8 @@ type withPrefix struct {
 	prefix string
 }
 
-func (e withPrefix) Errors() []errorWithFields {
-	errs := e.err.Errors()
-	res := make([]errorWithFields, 0, len(errs))
-	for _, err := range errs {
-		res = append(res, errorWithFields{
-			err:    fmt.Errorf("%s: %w", e.prefix, err.err),
-			fields: err.fields,
-		})
-	}
-	return res
+func (e withPrefix) Errors(o options) []errorWithFields {
+	return prefixErrors(e.prefix, e.err.Errors(o))
 }
 
 func (e withPrefix) Error() string {
@@ -259,16 +361,30 @@ func (e withPrefix) Unwrap() error {
 
 type withFields struct {
 	err    treeNode
-	fields Fields
+	fields FieldList
+	caller Stack // nil if stacks are disabled
 }
 
-func (e withFields) Errors() []errorWithFields {
-	errs := e.err.Errors()
+func (e withFields) Errors(o options) []errorWithFields {
+	errs := e.err.Errors(o)
+	fields, _ := resolveFields(e.fields)
 	res := make([]errorWithFields, 0, len(errs))
 	for _, err := range errs {
+		origins := err.origins
+		if o.provenance && len(fields) > 0 { // wrappers without fields aren't counted as levels
+			origins = append(origins[:len(origins):len(origins)], fieldOrigin{
+				fields: fields,
+				path:   err.path,
+				caller: e.caller,
+			})
+		}
 		res = append(res, errorWithFields{
-			err:    err.err,
-			fields: joinFields(e.fields, err.fields),
+			err:     err.err,
+			fields:  joinFields(fields, err.fields, o.policy),
+			stack:   err.stack,
+			cause:   err.cause,
+			path:    err.path,
+			origins: origins,
 		})
 	}
 	return res
@@ -282,14 +398,37 @@ func (e withFields) Unwrap() error {
 	return e.err
 }
 
+type withStack struct {
+	err   treeNode
+	stack *Stack
+}
+
+func (e withStack) Errors(o options) []errorWithFields {
+	errs := e.err.Errors(o)
+	for i := range errs {
+		if errs[i].stack == nil { // inner stack has priority
+			errs[i].stack = e.stack
+		}
+	}
+	return errs
+}
+
+func (e withStack) Error() string {
+	return e.err.Error()
+}
+
+func (e withStack) Unwrap() error {
+	return e.err
+}
+
 type many struct {
 	errors []treeNode
 }
 
-func (e many) Errors() []errorWithFields {
+func (e many) Errors(o options) []errorWithFields {
 	res := make([]errorWithFields, 0, len(e.errors))
 	for _, err := range e.errors {
-		res = append(res, err.Errors()...)
+		res = append(res, err.Errors(o)...)
 	}
 	return res
 }
@@ -302,10 +441,129 @@ func (e many) Unwrap() []error {
 	return res
 }
 
-func clone(fields Fields) Fields {
-	res := make(Fields, len(fields))
-	for k, v := range fields {
-		res[k] = v
+// IsStructured reports whether err or any error in its chain is built with this package,
+// i.e. it is a tree of errors, a [Leaf] or a foreign wrapper or multi-error containing one.
+// It is false for plain errors, so log adapters may keep them as is.
+func IsStructured(err error) bool {
+	for err != nil {
+		switch err.(type) { //nolint:errorlint // the chain is walked manually
+		case treeNode, errorWithFields, Leaf, *Leaf:
+			return true
+		}
+		if errs, ok := foreignErrors(err); ok {
+			for _, e := range errs {
+				if IsStructured(e) {
+					return true
+				}
+			}
+			return false
+		}
+		err = errors.Unwrap(err)
+	}
+	return false
+}
+
+// needsUnwrap reports whether err or any error in its Unwrap() error chain is built with this package
+// or is a foreign multi-error.
+func needsUnwrap(err error) bool {
+	for err != nil {
+		switch err.(type) { //nolint:errorlint // the chain is walked manually
+		case treeNode, errorWithFields, Leaf, *Leaf:
+			return true
+		}
+		if _, ok := foreignErrors(err); ok {
+			return true
+		}
+		err = errors.Unwrap(err)
+	}
+	return false
+}
+
+// foreignErrors returns errors contained in a foreign multi-error, e.g. created with errors.Join (go1.20+),
+// go.uber.org/multierr or github.com/hashicorp/go-multierror.
+func foreignErrors(err error) ([]error, bool) {
+	switch e := err.(type) { //nolint:errorlint // only err itself is checked, not its chain
+	case interface{ Unwrap() []error }:
+		return e.Unwrap(), true
+	case interface{ Errors() []error }: // go.uber.org/multierr
+		return e.Errors(), true
+	case interface{ WrappedErrors() []error }: // github.com/hashicorp/go-multierror
+		return e.WrappedErrors(), true
+	default:
+		return nil, false
+	}
+}
+
+func flattenForeign(errs []error, o options) []errorWithFields {
+	res := make([]errorWithFields, 0, len(errs))
+	for _, err := range errs {
+		res = append(res, wrapper{err: err}.Errors(o)...)
+	}
+	return res
+}
+
+// unwrapForeign returns leaves of inner error wrapped by foreign err (e.g. created with fmt.Errorf("ctx: %w")).
+// Message of err is kept as a prefix if it has the form "prefix: <message of inner>".
+// If err wraps a single leaf, err itself is the leaf error, so errors.As still finds it.
+// Otherwise, each leaf keeps the message of err with the message of inner replaced by the message of the leaf
+// and unwraps only to its own error.
+func unwrapForeign(err error, inner error, o options) []errorWithFields {
+	errs := wrapper{err: inner}.Errors(o)
+	msg, innerMsg := err.Error(), inner.Error()
+	if strings.HasSuffix(msg, ": "+innerMsg) {
+		errs = prefixErrors(strings.TrimSuffix(msg, ": "+innerMsg), errs)
+		if len(errs) > 1 {
+			return errs // each leaf already has the prefix in its message and unwraps to its own error
+		}
+	}
+	if len(errs) == 1 {
+		errs[0].err = err // the whole message of err is the message of the only leaf
+		return errs
+	}
+	for i := range errs {
+		leafMsg := errs[i].err.Error()
+		if strings.Contains(msg, innerMsg) {
+			leafMsg = strings.Replace(msg, innerMsg, leafMsg, 1)
+		} else {
+			leafMsg = msg + ": " + leafMsg
+		}
+		errs[i].err = foreignLeaf{
+			msg: leafMsg,
+			err: errs[i].err,
+		}
+	}
+	return errs
+}
+
+// foreignLeaf is one of several leaves wrapped by a foreign wrapper.
+// It has the message of the wrapper, but unwraps to its own error.
+type foreignLeaf struct {
+	msg string
+	err error
+}
+
+func (e foreignLeaf) Error() string {
+	return e.msg
+}
+
+func (e foreignLeaf) Unwrap() error {
+	return e.err
+}
+
+func prefixErrors(prefix string, errs []errorWithFields) []errorWithFields {
+	res := make([]errorWithFields, 0, len(errs))
+	for _, err := range errs {
+		path := make([]string, 0, len(err.path)+1)
+		path = append(path, prefix)
+		path = append(path, err.path...)
+		res = append(res, errorWithFields{
+			err:     fmt.Errorf("%s: %w", prefix, err.err),
+			fields:  err.fields,
+			stack:   err.stack,
+			cause:   err.cause,
+			path:    path,
+			origins: err.origins,
+		})
 	}
 	return res
 }
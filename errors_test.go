@@ -3,6 +3,9 @@ package errors_test
 import (
 	stderrors "errors"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -215,13 +218,73 @@ func TestWrap(t *testing.T) {
 		require.EqualError(t, errs[0], prefix+": "+newErr)
 	})
 
-	t.Run("fields are not accessible if error is wrapped with fmt", func(t *testing.T) {
+	t.Run("fields are accessible if error is wrapped with fmt", func(t *testing.T) {
 		t.Parallel()
 		errWithField := errors.New(newErr).WithField(key, value).E()
 		wrapped := fmt.Errorf("%w", errWithField)
 		fields := errors.FieldsFromError(wrapped)
-		require.NotNil(t, fields)
-		require.Empty(t, fields)
+		require.Equal(t, errors.Fields{key: value}, fields)
+
+		errs := errors.Errors(wrapped)
+		require.Len(t, errs, 1)
+		require.EqualError(t, errs[0], newErr)
+	})
+
+	t.Run("message of foreign wrapper is kept as prefix", func(t *testing.T) {
+		t.Parallel()
+		errWithField := errors.Wrap(prefix, errors.New(newErr).E()).WithField(key, value).E()
+		wrapped := errors.Wrap(prefix2, fmt.Errorf("ctx1: %w", fmt.Errorf("ctx2: %w", errWithField))).E()
+		require.EqualError(t, wrapped, prefix2+": ctx1: ctx2: "+prefix+": "+newErr)
+
+		fields := errors.FieldsFromError(wrapped)
+		require.Equal(t, errors.Fields{key: value}, fields)
+
+		errs := errors.Errors(wrapped)
+		require.Len(t, errs, 1)
+		require.EqualError(t, errs[0], prefix2+": ctx1: ctx2: "+prefix+": "+newErr)
+	})
+
+	t.Run("message of foreign wrapper is kept if it doesn't end with wrapped message", func(t *testing.T) {
+		t.Parallel()
+		errWithField := errors.New(newErr).WithField(key, value).E()
+		wrapped := fmt.Errorf("%w (ctx)", errWithField)
+		fields := errors.FieldsFromError(wrapped)
+		require.Equal(t, errors.Fields{key: value}, fields)
+
+		errs := errors.Errors(wrapped)
+		require.Len(t, errs, 1)
+		require.EqualError(t, errs[0], newErr+" (ctx)")
+		require.Empty(t, errors.Leaves(wrapped)[0].Path)
+	})
+
+	t.Run("foreign wrapper is kept in chain of leaf", func(t *testing.T) {
+		t.Parallel()
+		pathErr := &os.PathError{Op: "open", Path: "/file", Err: errors.New(newErr).WithField(key, value).E()}
+		for name, err := range map[string]error{
+			"foreign wrapper":         pathErr,
+			"wrapped foreign wrapper": errors.Wrap(prefix, fmt.Errorf("ctx: %w", pathErr)).E(),
+			"joined foreign wrapper":  errors.Join(pathErr, stderrors.New("another err")),
+		} {
+			err := err
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				errs := errors.Errors(err)
+				require.Equal(t, errors.Fields{key: value}, errors.FieldsFromError(errs[0]))
+				var target *os.PathError
+				require.True(t, stderrors.As(errs[0], &target))
+				require.Same(t, pathErr, target)
+			})
+		}
+	})
+
+	t.Run("foreign wrapper without fields is a leaf", func(t *testing.T) {
+		t.Parallel()
+		original := stderrors.New(newErr)
+		wrapped := fmt.Errorf("ctx: %w", original)
+		errs := errors.Errors(wrapped)
+		require.Len(t, errs, 1)
+		require.EqualError(t, errs[0], "ctx: "+newErr)
+		require.Equal(t, wrapped, stderrors.Unwrap(errs[0]))
 	})
 }
 
@@ -462,4 +525,305 @@ func TestMultipleErrors(t *testing.T) {
 		fields = errors.FieldsFromError(errs[2])
 		require.Equal(t, errors.Fields{key1: value4, key2: value2}, fields)
 	})
+
+	t.Run("foreign multi-errors are flattened", func(t *testing.T) {
+		t.Parallel()
+		for name, newMulti := range map[string]func(errs ...error) error{
+			"Unwrap() []error":        func(errs ...error) error { return unwrapMultiError(errs) },
+			"Errors() []error":        func(errs ...error) error { return multierrError(errs) },
+			"WrappedErrors() []error": func(errs ...error) error { return goMultiError(errs) },
+		} {
+			newMulti := newMulti
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				original1 := errors.New(newErr1).WithField(key1, value1).E()
+				original2 := errors.Wrap(prefix2, stderrors.New(newErr2)).WithField(key2, value2).E()
+				original3 := stderrors.New(newErr3)
+				multi := newMulti(original1, nil, newMulti(original2, original3))
+				err := errors.Wrap(prefix1, multi).WithField(key3, value3).E()
+
+				fields := errors.FieldsFromError(err)
+				require.Equal(t, errors.Fields{key1: value1, key3: value3}, fields)
+
+				errs := errors.Errors(err)
+				require.Len(t, errs, 3)
+				require.EqualError(t, errs[0], prefix1+": "+newErr1)
+				require.EqualError(t, errs[1], prefix1+": "+prefix2+": "+newErr2)
+				require.EqualError(t, errs[2], prefix1+": "+newErr3)
+
+				fields = errors.FieldsFromError(errs[1])
+				require.Equal(t, errors.Fields{key2: value2, key3: value3}, fields)
+
+				fields = errors.FieldsFromError(errs[2])
+				require.Equal(t, errors.Fields{key3: value3}, fields)
+			})
+		}
+	})
+
+	t.Run("foreign multi-error is flattened by Join", func(t *testing.T) {
+		t.Parallel()
+		original1 := errors.New(newErr1).WithField(key1, value1).E()
+		original2 := errors.New(newErr2).WithField(key2, value2).E()
+		err := errors.Join(unwrapMultiError{original1, original2}, stderrors.New(newErr3))
+
+		errs := errors.Errors(err)
+		require.Len(t, errs, 3)
+		require.EqualError(t, errs[0], newErr1)
+		require.EqualError(t, errs[1], newErr2)
+		require.EqualError(t, errs[2], newErr3)
+
+		fields := errors.FieldsFromError(errs[1])
+		require.Equal(t, errors.Fields{key2: value2}, fields)
+	})
+
+	t.Run("foreign multi-error wrapped with fmt is flattened", func(t *testing.T) {
+		t.Parallel()
+		original1, original2 := stderrors.New(newErr1), stderrors.New(newErr2)
+		multi := unwrapMultiError{original1, errors.Err(original2).WithField(key2, value2).E()}
+		err := fmt.Errorf("%s: %w", prefix1, multi)
+
+		errs := errors.Errors(err)
+		require.Len(t, errs, 2)
+		require.EqualError(t, errs[0], prefix1+": "+newErr1)
+		require.EqualError(t, errs[1], prefix1+": "+newErr2)
+		require.ErrorIs(t, errs[0], original1)
+		require.NotErrorIs(t, errs[0], original2)
+		require.ErrorIs(t, errs[1], original2)
+		require.NotErrorIs(t, errs[1], original1)
+
+		fields := errors.FieldsFromError(errs[1])
+		require.Equal(t, errors.Fields{key2: value2}, fields)
+	})
+
+	t.Run("message of foreign wrapper is kept for each leaf", func(t *testing.T) {
+		t.Parallel()
+		original1, original2 := stderrors.New(newErr1), stderrors.New(newErr2)
+		multi := unwrapMultiError{original1, errors.Err(original2).WithField(key2, value2).E()}
+		for name, tc := range map[string]struct {
+			err      error
+			expected []string
+		}{
+			"wrapped message inside": {
+				err:      fmt.Errorf("%w (ctx)", multi),
+				expected: []string{newErr1 + " (ctx)", newErr2 + " (ctx)"},
+			},
+			"without wrapped message": {
+				err:      customMessageError{err: multi},
+				expected: []string{"custom: " + newErr1, "custom: " + newErr2},
+			},
+		} {
+			tc := tc
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				errs := errors.Errors(tc.err)
+				require.Len(t, errs, 2)
+				require.EqualError(t, errs[0], tc.expected[0])
+				require.EqualError(t, errs[1], tc.expected[1])
+				require.ErrorIs(t, errs[1], original2)
+				require.NotErrorIs(t, errs[1], original1)
+				require.Equal(t, errors.Fields{key2: value2}, errors.FieldsFromError(errs[1]))
+			})
+		}
+	})
+
+	t.Run("empty foreign multi-error is a leaf", func(t *testing.T) {
+		t.Parallel()
+		err := unwrapMultiError{nil}
+		errs := errors.Errors(err)
+		require.Len(t, errs, 1)
+		require.EqualError(t, errs[0], err.Error())
+	})
+}
+
+// customMessageError is a foreign wrapper with a message which doesn't contain the wrapped message.
+type customMessageError struct {
+	err error
+}
+
+func (e customMessageError) Error() string {
+	return "custom"
+}
+
+func (e customMessageError) Unwrap() error {
+	return e.err
+}
+
+// unwrapMultiError has the same shape as errors created by errors.Join (go1.20+).
+type unwrapMultiError []error
+
+func (e unwrapMultiError) Error() string {
+	return joinMessages(e)
+}
+
+func (e unwrapMultiError) Unwrap() []error {
+	return e
+}
+
+// multierrError has the same shape as errors created by go.uber.org/multierr.
+type multierrError []error
+
+func (e multierrError) Error() string {
+	return joinMessages(e)
+}
+
+func (e multierrError) Errors() []error {
+	return e
+}
+
+// goMultiError has the same shape as errors created by github.com/hashicorp/go-multierror.
+type goMultiError []error
+
+func (e goMultiError) Error() string {
+	return joinMessages(e)
+}
+
+func (e goMultiError) WrappedErrors() []error {
+	return e
+}
+
+func joinMessages(errs []error) string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		if err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func TestRangeFields(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil error", func(t *testing.T) {
+		t.Parallel()
+		errors.RangeFields(nil, func(string, any) bool {
+			require.Fail(t, "must not be called")
+			return true
+		})
+	})
+
+	t.Run("same fields as FieldsFromError", func(t *testing.T) {
+		t.Parallel()
+		err := errors.Join(
+			errors.Wrap("prefix", errors.New("new err 1").WithField("key1", "value1").E()).WithField("key2", 2).E(),
+			errors.New("new err 2").WithField("key3", "value3").E(),
+		)
+		fields := errors.Fields{}
+		errors.RangeFields(err, func(key string, value any) bool {
+			fields[key] = value
+			return true
+		})
+		require.Equal(t, errors.FieldsFromError(err), fields)
+	})
+
+	t.Run("stop iteration", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithField("key1", "value1").WithField("key2", "value2").E()
+		calls := 0
+		errors.RangeFields(err, func(string, any) bool {
+			calls++
+			return false
+		})
+		require.Equal(t, 1, calls)
+	})
+}
+
+func TestIsStructured(t *testing.T) {
+	t.Parallel()
+	tree := errors.New("new err").WithField("key", "value").E()
+	leaf := errors.Leaves(tree)[0]
+	std := stderrors.New("std err")
+	require.True(t, errors.IsStructured(tree))
+	require.True(t, errors.IsStructured(errors.Err(std).E()))
+	require.True(t, errors.IsStructured(leaf))
+	require.True(t, errors.IsStructured(&leaf))
+	require.True(t, errors.IsStructured(fmt.Errorf("ctx: %w", tree)))
+	require.False(t, errors.IsStructured(nil))
+	require.False(t, errors.IsStructured(std))
+	require.False(t, errors.IsStructured(fmt.Errorf("ctx: %w", std)))
+}
+
+func TestImmutableBuilder(t *testing.T) {
+	t.Parallel()
+
+	t.Run("branches don't affect each other", func(t *testing.T) {
+		t.Parallel()
+		base := errors.New("new err").WithField("key", "value")
+		err1 := base.WithField("key1", 1).Wrap("prefix1").E()
+		err2 := base.With("key2", 2).WithStack().E()
+		err3 := base.Wrap("prefix3").WithGroup("group", errors.Field{Key: "key3", Value: 3}).E()
+		require.EqualError(t, err1, "prefix1: new err")
+		require.Equal(t, errors.Fields{"key": "value", "key1": 1}, errors.FieldsFromError(err1))
+		require.EqualError(t, err2, "new err")
+		require.Equal(t, errors.Fields{"key": "value", "key2": 2}, errors.FieldsFromError(err2))
+		require.EqualError(t, err3, "prefix3: new err")
+		require.Equal(t, errors.FieldList{
+			{Key: "key", Value: "value"},
+			{Key: "group", Value: errors.FieldList{{Key: "key3", Value: 3}}},
+		}, errors.OrderedFields(err3))
+		require.EqualError(t, base.E(), "new err")
+		require.Equal(t, errors.Fields{"key": "value"}, errors.FieldsFromError(base.E()))
+	})
+
+	t.Run("receiver doesn't change", func(t *testing.T) {
+		t.Parallel()
+		builder := errors.Err(stderrors.New("std err"))
+		builder.Wrap("prefix")
+		builder.WithFields(errors.Fields{"key": "value"})
+		builder.WithField("key", "value")
+		builder.With("key", "value")
+		builder.WithGroup("group", errors.Field{Key: "key", Value: "value"})
+		builder.WithStruct(Customer{ID: "c1", Name: "name"})
+		builder.WithStack()
+		builder.WithSnapshot(100)
+		require.EqualError(t, builder.E(), "std err")
+		require.Empty(t, errors.FieldsFromError(builder.E()))
+		require.Nil(t, errors.Leaves(builder.E())[0].Stack)
+	})
+
+	t.Run("concurrent derivation", func(t *testing.T) {
+		t.Parallel()
+		base := errors.New("new err").WithField("key", "value")
+		errs := make([]error, 10)
+		var wg sync.WaitGroup
+		for i := range errs {
+			i := i
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = base.WithField("i", i).Wrap(fmt.Sprintf("prefix%d", i)).E()
+			}()
+		}
+		wg.Wait()
+		for i, err := range errs {
+			require.EqualError(t, err, fmt.Sprintf("prefix%d: new err", i))
+			require.Equal(t, errors.Fields{"key": "value", "i": i}, errors.FieldsFromError(err))
+		}
+		require.Equal(t, errors.Fields{"key": "value"}, errors.FieldsFromError(base.E()))
+	})
+
+	t.Run("concurrent extraction", func(t *testing.T) {
+		t.Parallel()
+		err := errors.Join(
+			errors.New("err1").WithField("key1", 1).E(),
+			errors.New("err2").WithGroup("group", errors.Field{Key: "key2", Value: 2}).E(),
+		)
+		err = errors.WithField(err, "key", "value").Wrap("prefix").E()
+		expected := errors.LeafFields(err)
+		results := make([][]errors.Fields, 10)
+		var wg sync.WaitGroup
+		for i := range results {
+			i := i
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = fmt.Sprintf("%+v", err)
+				results[i] = errors.LeafFields(err)
+			}()
+		}
+		wg.Wait()
+		for _, fields := range results {
+			require.Equal(t, expected, fields)
+		}
+	})
 }
@@ -0,0 +1,16 @@
+// Command errorsvet checks arguments of errors.With and ErrorBuilder.With, see package errorsvet.
+// It's intended to be run by go vet:
+//
+//	go install github.com/maratori/errors/errorsvet/cmd/errorsvet@latest
+//	go vet -vettool=$(which errorsvet) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/maratori/errors/errorsvet"
+)
+
+func main() {
+	singlechecker.Main(errorsvet.Analyzer)
+}
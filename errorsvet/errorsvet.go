@@ -0,0 +1,98 @@
+// Package errorsvet provides analyzer which checks arguments of errors.With and ErrorBuilder.With
+// the same way as the slog check of go vet does for [slog.Logger.With].
+package errorsvet
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const (
+	packagePath = "github.com/maratori/errors"
+	slogPath    = "log/slog"
+)
+
+const doc = `check arguments of errors.With and ErrorBuilder.With
+
+The analyzer reports a key without a value and arguments which are neither
+a string key, errors.Key, errors.Field nor slog.Attr. Such arguments are added with
+"!BADKEY" key at runtime.`
+
+// Analyzer reports mismatched key/value pairs passed to errors.With and ErrorBuilder.With.
+//
+//nolint:gochecknoglobals,exhaustruct // global analyzer is the convention of go/analysis, other fields are optional
+var Analyzer = &analysis.Analyzer{
+	Name: "errorsvet",
+	Doc:  doc,
+	Run:  run,
+}
+
+//nolint:nilnil // analyzer has no result
+func run(pass *analysis.Pass) (any, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				checkCall(pass, call)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+func checkCall(pass *analysis.Pass, call *ast.CallExpr) {
+	fn := typeutil.StaticCallee(pass.TypesInfo, call)
+	if fn == nil || call.Ellipsis.IsValid() { // arguments passed as a slice can't be checked
+		return
+	}
+	var args []ast.Expr
+	switch fn.FullName() {
+	case packagePath + ".With":
+		if len(call.Args) == 0 {
+			return
+		}
+		args = call.Args[1:] // skip error
+	case "(*" + packagePath + ".ErrorBuilder).With":
+		args = call.Args
+	default:
+		return
+	}
+	for len(args) > 0 {
+		arg := args[0]
+		t := pass.TypesInfo.TypeOf(arg)
+		switch {
+		case t == nil, types.IsInterface(t):
+			return // the rest can't be checked statically, e.g. value of any may be a key
+		case isString(t), isNamed(t, packagePath, "Key"):
+			if len(args) == 1 {
+				pass.ReportRangef(arg, "call to %s has a key without a value", fn.Name())
+				return
+			}
+			args = args[2:]
+			continue
+		case isNamed(t, packagePath, "Field"), isNamed(t, slogPath, "Attr"):
+		default:
+			pass.ReportRangef(arg, "%s arg %q should be a string key, errors.Key, errors.Field or slog.Attr "+
+				"(possible missing key or value)", fn.Name(), types.ExprString(arg))
+		}
+		args = args[1:]
+	}
+}
+
+// isString reports whether t is string, named string types aren't keys at runtime.
+func isString(t types.Type) bool {
+	b, ok := t.(*types.Basic)
+	return ok && (b.Kind() == types.String || b.Kind() == types.UntypedString)
+}
+
+func isNamed(t types.Type, pkgPath string, name string) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == pkgPath && obj.Name() == name
+}
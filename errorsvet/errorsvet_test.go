@@ -0,0 +1,14 @@
+package errorsvet_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/maratori/errors/errorsvet"
+)
+
+func TestAnalyzer(t *testing.T) {
+	t.Parallel()
+	analysistest.Run(t, analysistest.TestData(), errorsvet.Analyzer, "a")
+}
@@ -0,0 +1,32 @@
+package a
+
+import (
+	"log/slog"
+
+	"github.com/maratori/errors"
+)
+
+type key string
+
+func _(err error, k string, v any, args []any) {
+	errors.New("msg").With()
+	errors.New("msg").With("key", 1, k, 2)
+	errors.New("msg").With("key", 1, errors.Field{Key: "key", Value: 2})
+	errors.New("msg").With("key")                  // want `call to With has a key without a value`
+	errors.New("msg").With("key1", 1, "key2")      // want `call to With has a key without a value`
+	errors.New("msg").With(1)                      // want `With arg "1" should be a string key`
+	errors.New("msg").With(key(k), 1)              // want `arg "key\(k\)" should` `arg "1" should`
+	errors.New("msg").With("key", 1, 2, "value")   // want `arg "2" should` `key without a value`
+	errors.New("msg").With("key", 1, err, "value") // value of interface type may be a key
+	errors.New("msg").With(slog.Int("key", 1), "key2", 2, slog.Group("group", "key", 3))
+	errors.New("msg").With(slog.Int("key", 1), 2) // want `With arg "2" should be a string key`
+	errors.New("msg").With(v, 1, 2)
+	errors.New("msg").With(args...)
+	errors.New("msg").With(errors.NewKey[int]("key"), 1, "key2", 2)
+	errors.New("msg").With("key", 1, errors.NewKey[int]("key2")) // want `call to With has a key without a value`
+
+	errors.With(err)
+	errors.With(err, "key", 1)
+	errors.With(err, "key") // want `call to With has a key without a value`
+	errors.With(err, args...)
+}
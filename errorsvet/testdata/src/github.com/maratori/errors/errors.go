@@ -0,0 +1,29 @@
+// Package errors is a stub of github.com/maratori/errors for tests.
+package errors
+
+type Field struct {
+	Key   string
+	Value any
+}
+
+type Key[T any] struct {
+	name string
+}
+
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{name: name}
+}
+
+type ErrorBuilder struct{}
+
+func (e *ErrorBuilder) With(args ...any) *ErrorBuilder {
+	return e
+}
+
+func With(err error, args ...any) *ErrorBuilder {
+	return nil
+}
+
+func New(msg string) *ErrorBuilder {
+	return nil
+}
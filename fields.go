@@ -0,0 +1,317 @@
+package errors
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Field is a key/value pair which can be attached to an error with ErrorBuilder.With.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// FieldList is a list of fields with unique keys ordered by insertion.
+// Fields of inner errors go first, because they are added earlier.
+// Fields added by one WithFields call are sorted by key, because map order is random.
+type FieldList []Field
+
+// Group returns field which value is a group of fields, similar to [slog.Group].
+// The value is FieldList, so groups may be nested.
+// If both inner and outer errors have a group with the same key, groups are merged key by key
+// with the same priority as other fields.
+func Group(name string, fields ...Field) Field {
+	return Field{
+		Key:   name,
+		Value: uniqueFields(fields),
+	}
+}
+
+// Get returns value of the field with the key.
+func (l FieldList) Get(key string) (any, bool) {
+	for _, f := range l {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Map returns fields as a map. It never returns nil.
+func (l FieldList) Map() Fields {
+	res := make(Fields, len(l))
+	for _, f := range l {
+		res[f.Key] = f.Value
+	}
+	return res
+}
+
+// Flatten returns fields where each group is replaced by its fields
+// with keys prefixed by the group name and sep, e.g. "db.table" for sep ".".
+// Flattened keys are not checked for collisions with other keys.
+func (l FieldList) Flatten(sep string) FieldList {
+	return l.appendFlatten(make(FieldList, 0, len(l)), "", sep)
+}
+
+func (l FieldList) appendFlatten(res FieldList, prefix string, sep string) FieldList {
+	for _, f := range l {
+		if group, ok := f.Value.(FieldList); ok {
+			res = group.appendFlatten(res, prefix+f.Key+sep, sep)
+			continue
+		}
+		res = append(res, Field{
+			Key:   prefix + f.Key,
+			Value: f.Value,
+		})
+	}
+	return res
+}
+
+// RenameReserved returns fields where each key from reserved is replaced with the first free key
+// of the form "key#N", the same as KeepBoth does. Log adapters use it, so fields don't collide
+// with keys they add next to fields, e.g. "message". Keys of groups are not checked.
+// The list is returned as is if it has no reserved keys.
+func (l FieldList) RenameReserved(reserved ...string) FieldList {
+	var res FieldList
+	for i, f := range l {
+		if !isReserved(f.Key, reserved) {
+			continue
+		}
+		if res == nil {
+			res = l.clone()
+		}
+		res[i].Key = res.freeKey(f.Key, nil)
+	}
+	if res == nil {
+		return l
+	}
+	return res
+}
+
+func isReserved(key string, reserved []string) bool {
+	for _, r := range reserved {
+		if key == r {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderedFields returns the same fields as FieldsFromError in order of insertion.
+func OrderedFields(err error, opts ...Option) FieldList {
+	errs := leavesOf(err, opts)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs[0].fields.clone()
+}
+
+func (l FieldList) clone() FieldList {
+	if l == nil {
+		return nil
+	}
+	return append(make(FieldList, 0, len(l)), l...)
+}
+
+// fieldsFromMap returns fields sorted by key.
+func fieldsFromMap(fields Fields) FieldList {
+	res := make(FieldList, 0, len(fields))
+	for k, v := range fields {
+		res = append(res, Field{
+			Key:   k,
+			Value: v,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Key < res[j].Key
+	})
+	return res
+}
+
+// uniqueFields returns fields with unique keys. The last value of duplicated key is kept at the first position.
+func uniqueFields(fields []Field) FieldList {
+	res := make(FieldList, 0, len(fields))
+	for _, f := range fields {
+		if i := res.index(f.Key); i >= 0 {
+			res[i].Value = f.Value
+			continue
+		}
+		res = append(res, f)
+	}
+	return res
+}
+
+// badKey is the key of a value without a key passed to ErrorBuilder.With, the same as in [slog.Logger.With].
+const badKey = "!BADKEY"
+
+// argsToFields converts arguments of ErrorBuilder.With into fields with unique keys.
+func argsToFields(args []any) FieldList {
+	fields := make([]Field, 0, len(args))
+	for len(args) > 0 {
+		if key, ok := argKey(args[0]); ok {
+			if len(args) == 1 {
+				fields = append(fields, Field{Key: badKey, Value: key})
+				break
+			}
+			fields = append(fields, Field{Key: key, Value: args[1]})
+			args = args[2:]
+			continue
+		}
+		switch arg := args[0].(type) {
+		case Field:
+			fields = append(fields, arg)
+		default:
+			if attrs, ok := attrFields(arg); ok {
+				fields = append(fields, attrs...)
+			} else {
+				fields = append(fields, Field{Key: badKey, Value: arg})
+			}
+		}
+		args = args[1:]
+	}
+	return uniqueFields(fields)
+}
+
+// fieldKey is implemented by Key[T] of any T, so it can be passed to ErrorBuilder.With instead of a string key.
+type fieldKey interface {
+	keyName() string
+}
+
+// argKey returns the key if arg is a string or Key[T].
+func argKey(arg any) (string, bool) {
+	switch a := arg.(type) {
+	case string:
+		return a, true
+	case fieldKey:
+		return a.keyName(), true
+	default:
+		return "", false
+	}
+}
+
+func (l FieldList) index(key string) int {
+	for i, f := range l {
+		if f.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Key is a typed key of a field. Use NewKey to create it.
+// It ensures the same type of value is used to set and get the field.
+type Key[T any] struct {
+	name string
+}
+
+// NewKey returns typed key with the given name, e.g.
+//
+//	var OrderID = errors.NewKey[uuid.UUID]("order_id")
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{
+		name: name,
+	}
+}
+
+// Name returns the name of the field used in Fields.
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+func (k Key[T]) keyName() string {
+	return k.name
+}
+
+// Field returns field with the key and value to be passed to ErrorBuilder.With.
+func (k Key[T]) Field(value T) Field {
+	return Field{
+		Key:   k.name,
+		Value: value,
+	}
+}
+
+// Get searches leaves of the errors tree in order of Errors and returns value of the field
+// from the first leaf having it. For an error with one leaf, it's the same as FieldsFromError.
+// It returns false if there is no such field or its value has a different type.
+func Get[T any](err error, key Key[T], opts ...Option) (T, bool) {
+	for _, leaf := range leavesOf(err, opts) {
+		if value, ok := leaf.fields.Get(key.name); ok {
+			res, ok := value.(T)
+			return res, ok
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// MergeStrategy defines how MergedFields resolves a key present in several leaves.
+type MergeStrategy int
+
+const (
+	// KeepFirst keeps the value from the first leaf having the key.
+	KeepFirst MergeStrategy = iota
+	// KeepLast keeps the value from the last leaf having the key.
+	KeepLast
+	// CollectAll puts values from all leaves having the key into []any in order of leaves.
+	// The value is []any even if the key is present in one leaf only.
+	CollectAll
+)
+
+func (s MergeStrategy) valid() bool {
+	return s >= KeepFirst && s <= CollectAll
+}
+
+// MergedFields returns fields of all leaves of the errors tree (see Errors) merged into one map.
+// It panics if strategy is unknown.
+func MergedFields(err error, strategy MergeStrategy, opts ...Option) Fields {
+	if !strategy.valid() {
+		panic("misuse of errors.MergedFields: unknown merge strategy")
+	}
+	res := Fields{}
+	for _, leaf := range leavesOf(err, opts) {
+		for _, f := range leaf.fields {
+			k, v := f.Key, f.Value
+			switch strategy {
+			case KeepFirst:
+				if _, ok := res[k]; !ok {
+					res[k] = v
+				}
+			case KeepLast:
+				res[k] = v
+			case CollectAll:
+				values, _ := res[k].([]any) // nil for the first value
+				res[k] = append(values, v)
+			}
+		}
+	}
+	return res
+}
+
+// CommonFields returns fields present in every leaf of the errors tree (see Errors) with equal values.
+// Values are compared with [reflect.DeepEqual].
+func CommonFields(err error, opts ...Option) Fields {
+	errs := leavesOf(err, opts)
+	if len(errs) == 0 {
+		return Fields{}
+	}
+	res := errs[0].fields.Map()
+	for _, leaf := range errs[1:] {
+		for k, v := range res {
+			if other, ok := leaf.fields.Get(k); !ok || !reflect.DeepEqual(v, other) {
+				delete(res, k)
+			}
+		}
+	}
+	return res
+}
+
+// LeafFields returns fields of each leaf of the errors tree.
+// The result is aligned with Errors: LeafFields(err)[i] contains fields of Errors(err)[i].
+func LeafFields(err error, opts ...Option) []Fields {
+	errs := leavesOf(err, opts)
+	res := make([]Fields, 0, len(errs))
+	for _, leaf := range errs {
+		res = append(res, leaf.fields.Map())
+	}
+	return res
+}
@@ -0,0 +1,463 @@
+package errors_test
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maratori/errors"
+)
+
+func TestOrderedFields(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil error", func(t *testing.T) {
+		t.Parallel()
+		require.Nil(t, errors.OrderedFields(nil))
+	})
+
+	t.Run("foreign error", func(t *testing.T) {
+		t.Parallel()
+		require.Empty(t, errors.OrderedFields(stderrors.New("new err")))
+	})
+
+	t.Run("inner fields go first", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").
+			WithFields(errors.Fields{"key3": 3, "key1": 1}).
+			Wrap("prefix").
+			With(errors.Field{Key: "key4", Value: 4}, errors.Field{Key: "key2", Value: 2}).
+			E()
+		require.Equal(t, errors.FieldList{
+			{Key: "key1", Value: 1},
+			{Key: "key3", Value: 3},
+			{Key: "key4", Value: 4},
+			{Key: "key2", Value: 2},
+		}, errors.OrderedFields(err))
+	})
+
+	t.Run("inner field keeps its position and value", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").
+			WithField("key1", 1).
+			WithField("key2", 2).
+			WithFields(errors.Fields{"key1": "outer", "key3": 3}).
+			E()
+		require.Equal(t, errors.FieldList{
+			{Key: "key1", Value: 1},
+			{Key: "key2", Value: 2},
+			{Key: "key3", Value: 3},
+		}, errors.OrderedFields(err))
+	})
+
+	t.Run("duplicated key in one call", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").With(
+			errors.Field{Key: "key1", Value: 1},
+			errors.Field{Key: "key2", Value: 2},
+			errors.Field{Key: "key1", Value: 3},
+		).E()
+		require.Equal(t, errors.FieldList{
+			{Key: "key1", Value: 3},
+			{Key: "key2", Value: 2},
+		}, errors.OrderedFields(err))
+	})
+
+	t.Run("range fields in order", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithField("key2", 2).WithField("key1", 1).E()
+		var keys []string
+		errors.RangeFields(err, func(key string, _ any) bool {
+			keys = append(keys, key)
+			return true
+		})
+		require.Equal(t, []string{"key2", "key1"}, keys)
+	})
+
+	t.Run("result is a copy", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithField("key", "value").E()
+		fields := errors.OrderedFields(err)
+		fields[0].Value = "changed"
+		require.Equal(t, errors.Fields{"key": "value"}, errors.FieldsFromError(err))
+	})
+}
+
+func TestWith(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil error", func(t *testing.T) {
+		t.Parallel()
+		require.Nil(t, errors.With(nil, "key", 1))
+	})
+
+	t.Run("key value pairs and fields", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").With("key2", 2, errors.Field{Key: "key1", Value: 1}, "key3", nil).E()
+		require.Equal(t, errors.FieldList{
+			{Key: "key2", Value: 2},
+			{Key: "key1", Value: 1},
+			{Key: "key3", Value: nil},
+		}, errors.OrderedFields(err))
+	})
+
+	t.Run("package function", func(t *testing.T) {
+		t.Parallel()
+		inner := stderrors.New("new err")
+		err := errors.With(inner, "key", 1).E()
+		require.Equal(t, "new err", err.Error())
+		require.ErrorIs(t, err, inner)
+		require.Equal(t, errors.Fields{"key": 1}, errors.FieldsFromError(err))
+	})
+
+	t.Run("key without value", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").With("key1", 1, "key2").E()
+		require.Equal(t, errors.FieldList{
+			{Key: "key1", Value: 1},
+			{Key: "!BADKEY", Value: "key2"},
+		}, errors.OrderedFields(err))
+	})
+
+	t.Run("value without key", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").With(1, "key", 2).E()
+		require.Equal(t, errors.FieldList{
+			{Key: "!BADKEY", Value: 1},
+			{Key: "key", Value: 2},
+		}, errors.OrderedFields(err))
+	})
+
+	t.Run("duplicated key", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").With("key1", 1, "key2", 2, "key1", 3).E()
+		require.Equal(t, errors.FieldList{
+			{Key: "key1", Value: 3},
+			{Key: "key2", Value: 2},
+		}, errors.OrderedFields(err))
+	})
+}
+
+func TestFieldList(t *testing.T) {
+	t.Parallel()
+	fields := errors.FieldList{
+		{Key: "key1", Value: 1},
+		{Key: "key2", Value: nil},
+	}
+
+	t.Run("get", func(t *testing.T) {
+		t.Parallel()
+		value, ok := fields.Get("key1")
+		require.True(t, ok)
+		require.Equal(t, 1, value)
+
+		value, ok = fields.Get("key2")
+		require.True(t, ok)
+		require.Nil(t, value)
+
+		value, ok = fields.Get("key3")
+		require.False(t, ok)
+		require.Nil(t, value)
+	})
+
+	t.Run("map", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, errors.Fields{"key1": 1, "key2": nil}, fields.Map())
+		require.Equal(t, errors.Fields{}, errors.FieldList(nil).Map())
+	})
+
+	t.Run("rename reserved", func(t *testing.T) {
+		t.Parallel()
+		reserved := errors.FieldList{
+			{Key: "message", Value: 1},
+			{Key: "message#1", Value: 2},
+			{Key: "key", Value: errors.FieldList{{Key: "message", Value: 3}}},
+			{Key: "leaves", Value: 4},
+		}
+		require.Equal(t, errors.FieldList{
+			{Key: "message#2", Value: 1},
+			{Key: "message#1", Value: 2},
+			{Key: "key", Value: errors.FieldList{{Key: "message", Value: 3}}},
+			{Key: "leaves#1", Value: 4},
+		}, reserved.RenameReserved("message", "leaves"))
+		require.Equal(t, "message", reserved[0].Key, "input is not changed")
+		require.Equal(t, fields, fields.RenameReserved("message", "leaves"))
+	})
+}
+
+func TestGroup(t *testing.T) {
+	t.Parallel()
+
+	t.Run("with group", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").
+			WithGroup("db", errors.Field{Key: "table", Value: "orders"}, errors.Group("query",
+				errors.Field{Key: "id", Value: 1},
+			)).
+			E()
+		require.Equal(t, errors.FieldList{
+			{Key: "db", Value: errors.FieldList{
+				{Key: "table", Value: "orders"},
+				{Key: "query", Value: errors.FieldList{
+					{Key: "id", Value: 1},
+				}},
+			}},
+		}, errors.OrderedFields(err))
+	})
+
+	t.Run("groups are merged key by key", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").
+			WithGroup("db", errors.Field{Key: "table", Value: "orders"}, errors.Field{Key: "query", Value: "inner"}).
+			Wrap("prefix").
+			WithGroup("db", errors.Field{Key: "query", Value: "outer"}, errors.Field{Key: "host", Value: "localhost"}).
+			WithGroup("http", errors.Field{Key: "method", Value: "GET"}).
+			E()
+		require.Equal(t, errors.FieldList{
+			{Key: "db", Value: errors.FieldList{
+				{Key: "table", Value: "orders"},
+				{Key: "query", Value: "inner"},
+				{Key: "host", Value: "localhost"},
+			}},
+			{Key: "http", Value: errors.FieldList{
+				{Key: "method", Value: "GET"},
+			}},
+		}, errors.OrderedFields(err))
+	})
+
+	t.Run("inner value replaces outer group", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").
+			WithField("db", "inner").
+			WithGroup("db", errors.Field{Key: "table", Value: "orders"}).
+			E()
+		require.Equal(t, errors.Fields{"db": "inner"}, errors.FieldsFromError(err))
+	})
+
+	t.Run("flatten", func(t *testing.T) {
+		t.Parallel()
+		fields := errors.FieldList{
+			{Key: "key", Value: 1},
+			errors.Group("db",
+				errors.Field{Key: "table", Value: "orders"},
+				errors.Group("query", errors.Field{Key: "id", Value: 2}),
+			),
+		}
+		require.Equal(t, errors.FieldList{
+			{Key: "key", Value: 1},
+			{Key: "db_table", Value: "orders"},
+			{Key: "db_query_id", Value: 2},
+		}, fields.Flatten("_"))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+		data, err := json.Marshal(errors.FieldList{
+			{Key: "key", Value: 1},
+			errors.Group("db", errors.Field{Key: "table", Value: "orders"}, errors.Field{Key: "id", Value: 2}),
+		})
+		require.NoError(t, err)
+		require.Equal(t, `{"key":1,"db":{"table":"orders","id":2}}`, string(data))
+	})
+
+	t.Run("tree output", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithGroup("db", errors.Field{Key: "table", Value: "orders"}).E()
+		require.Equal(t, "new err\nleaf 0: new err\n    cause: *errors.errorString\n    fields: db.table=orders",
+			fmt.Sprintf("%+v", err),
+		)
+	})
+}
+
+func TestKey(t *testing.T) {
+	t.Parallel()
+	type orderID string
+	var (
+		orderKey = errors.NewKey[orderID]("order_id")
+		countKey = errors.NewKey[int]("count")
+	)
+
+	t.Run("name", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, "order_id", orderKey.Name())
+	})
+
+	t.Run("with and get", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").With(orderKey.Field("42"), countKey.Field(1)).Wrap("prefix").E()
+		require.Equal(t, errors.Fields{"order_id": orderID("42"), "count": 1}, errors.FieldsFromError(err))
+
+		order, ok := errors.Get(err, orderKey)
+		require.True(t, ok)
+		require.Equal(t, orderID("42"), order)
+
+		count, ok := errors.Get(err, countKey)
+		require.True(t, ok)
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("key of key/value pair", func(t *testing.T) {
+		t.Parallel()
+		err := errors.With(errors.New("new err").E(), orderKey, orderID("42"), countKey).E()
+		require.Equal(t, errors.FieldList{
+			{Key: "order_id", Value: orderID("42")},
+			{Key: "!BADKEY", Value: "count"},
+		}, errors.OrderedFields(err))
+
+		order, ok := errors.Get(err, orderKey)
+		require.True(t, ok)
+		require.Equal(t, orderID("42"), order)
+	})
+
+	t.Run("inner field has priority", func(t *testing.T) {
+		t.Parallel()
+		inner := errors.New("new err").With(countKey.Field(1)).E()
+		err := errors.Wrap("prefix", inner).With(countKey.Field(2)).E()
+		count, ok := errors.Get(err, countKey)
+		require.True(t, ok)
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+		t.Parallel()
+		count, ok := errors.Get(errors.New("new err").E(), countKey)
+		require.False(t, ok)
+		require.Zero(t, count)
+
+		count, ok = errors.Get(nil, countKey)
+		require.False(t, ok)
+		require.Zero(t, count)
+	})
+
+	t.Run("joined errors", func(t *testing.T) {
+		t.Parallel()
+		err := errors.Join(
+			errors.New("err1").E(),
+			errors.New("err2").With(countKey, 2).E(),
+			errors.New("err3").With(countKey, 3).E(),
+		)
+		count, ok := errors.Get(err, countKey)
+		require.True(t, ok)
+		require.Equal(t, 2, count)
+	})
+
+	t.Run("different type", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithField("count", "1").E()
+		count, ok := errors.Get(err, countKey)
+		require.False(t, ok)
+		require.Zero(t, count)
+	})
+
+	t.Run("nil builder", func(t *testing.T) {
+		t.Parallel()
+		require.NoError(t, errors.Err(nil).With(countKey.Field(1)).E())
+	})
+}
+
+func TestMergedFields(t *testing.T) {
+	t.Parallel()
+	joined := errors.Wrap("prefix", errors.Join(
+		errors.New("new err 1").WithField("key1", "value1").WithField("key2", "value2").E(),
+		stderrors.New("new err 2"),
+		errors.New("new err 3").WithField("key1", "value3").E(),
+	)).WithField("key3", "value4").E()
+
+	t.Run("nil error", func(t *testing.T) {
+		t.Parallel()
+		fields := errors.MergedFields(nil, errors.KeepFirst)
+		require.NotNil(t, fields)
+		require.Empty(t, fields)
+	})
+
+	t.Run("keep first", func(t *testing.T) {
+		t.Parallel()
+		fields := errors.MergedFields(joined, errors.KeepFirst)
+		require.Equal(t, errors.Fields{"key1": "value1", "key2": "value2", "key3": "value4"}, fields)
+	})
+
+	t.Run("keep last", func(t *testing.T) {
+		t.Parallel()
+		fields := errors.MergedFields(joined, errors.KeepLast)
+		require.Equal(t, errors.Fields{"key1": "value3", "key2": "value2", "key3": "value4"}, fields)
+	})
+
+	t.Run("collect all", func(t *testing.T) {
+		t.Parallel()
+		fields := errors.MergedFields(joined, errors.CollectAll)
+		require.Equal(t, errors.Fields{
+			"key1": []any{"value1", "value3"},
+			"key2": []any{"value2"},
+			"key3": []any{"value4", "value4", "value4"},
+		}, fields)
+	})
+
+	t.Run("unknown strategy", func(t *testing.T) {
+		t.Parallel()
+		require.PanicsWithValue(t, "misuse of errors.MergedFields: unknown merge strategy", func() {
+			errors.MergedFields(joined, errors.MergeStrategy(-1))
+		})
+		require.PanicsWithValue(t, "misuse of errors.MergedFields: unknown merge strategy", func() {
+			errors.MergedFields(errors.New("new err").E(), errors.MergeStrategy(-1))
+		})
+	})
+}
+
+func TestCommonFields(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil error", func(t *testing.T) {
+		t.Parallel()
+		fields := errors.CommonFields(nil)
+		require.NotNil(t, fields)
+		require.Empty(t, fields)
+	})
+
+	t.Run("single error", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithField("key", "value").E()
+		require.Equal(t, errors.Fields{"key": "value"}, errors.CommonFields(err))
+	})
+
+	t.Run("joined errors", func(t *testing.T) {
+		t.Parallel()
+		err := errors.Wrap("prefix", errors.Join(
+			errors.New("new err 1").WithField("key1", "value1").WithField("key2", []int{1}).E(),
+			errors.New("new err 2").WithField("key1", "value2").WithField("key2", []int{1}).E(),
+			errors.New("new err 3").WithField("key2", []int{1}).E(),
+		)).WithField("key3", "value3").E()
+		require.Equal(t, errors.Fields{"key2": []int{1}, "key3": "value3"}, errors.CommonFields(err))
+	})
+}
+
+func TestLeafFields(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil error", func(t *testing.T) {
+		t.Parallel()
+		require.Empty(t, errors.LeafFields(nil))
+	})
+
+	t.Run("aligned with Errors", func(t *testing.T) {
+		t.Parallel()
+		err := errors.Join(
+			errors.New("new err 1").WithField("key1", "value1").E(),
+			stderrors.New("new err 2"),
+			errors.New("new err 3").WithField("key1", "value3").E(),
+		)
+		fields := errors.LeafFields(err)
+		require.Equal(t, []errors.Fields{
+			{"key1": "value1"},
+			{},
+			{"key1": "value3"},
+		}, fields)
+		errs := errors.Errors(err)
+		require.Len(t, errs, len(fields))
+		for i := range errs {
+			require.Equal(t, errors.FieldsFromError(errs[i]), fields[i])
+		}
+	})
+}
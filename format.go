@@ -0,0 +1,128 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+//nolint:exhaustruct // false positive
+var (
+	_ fmt.Formatter     = wrapper{}
+	_ fmt.Formatter     = withPrefix{}
+	_ fmt.Formatter     = withFields{}
+	_ fmt.Formatter     = withStack{}
+	_ fmt.Formatter     = many{}
+	_ xerrors.Formatter = wrapper{}
+	_ xerrors.Formatter = withPrefix{}
+	_ xerrors.Formatter = withFields{}
+	_ xerrors.Formatter = withStack{}
+	_ xerrors.Formatter = many{}
+)
+
+func (e wrapper) Format(s fmt.State, verb rune)    { format(e, s, verb) }
+func (e withPrefix) Format(s fmt.State, verb rune) { format(e, s, verb) }
+func (e withFields) Format(s fmt.State, verb rune) { format(e, s, verb) }
+func (e withStack) Format(s fmt.State, verb rune)  { format(e, s, verb) }
+func (e many) Format(s fmt.State, verb rune)       { format(e, s, verb) }
+
+func (e wrapper) FormatError(p xerrors.Printer) error    { return formatError(e, p) }
+func (e withPrefix) FormatError(p xerrors.Printer) error { return formatError(e, p) }
+func (e withFields) FormatError(p xerrors.Printer) error { return formatError(e, p) }
+func (e withStack) FormatError(p xerrors.Printer) error  { return formatError(e, p) }
+func (e many) FormatError(p xerrors.Printer) error       { return formatError(e, p) }
+
+func (e wrapper) GoString() string {
+	return fmt.Sprintf("errors.wrapper{err:%#v}", e.err)
+}
+
+func (e withPrefix) GoString() string {
+	return fmt.Sprintf("errors.withPrefix{prefix:%q, err:%#v}", e.prefix, e.err)
+}
+
+func (e withFields) GoString() string {
+	return fmt.Sprintf("errors.withFields{fields:%#v, err:%#v}", e.fields, e.err)
+}
+
+func (e withStack) GoString() string {
+	return fmt.Sprintf("errors.withStack{stack:%d frames, err:%#v}", len(*e.stack), e.err)
+}
+
+func (e many) GoString() string {
+	errs := make([]string, 0, len(e.errors))
+	for _, err := range e.errors {
+		errs = append(errs, fmt.Sprintf("%#v", err))
+	}
+	return "errors.many{errors:[" + strings.Join(errs, ", ") + "]}"
+}
+
+type formattable interface {
+	treeNode
+	fmt.GoStringer
+}
+
+// format supports following verbs:
+//
+//	%s, %v - error message
+//	%q     - quoted error message
+//	%+v    - error message followed by prefixes, fields (groups are flattened with "."), type of cause
+//	         and stack of each leaf
+//	%#v    - Go-syntax representation of the tree
+func format(err formattable, s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('#'):
+		_, _ = io.WriteString(s, err.GoString())
+	case verb == 'v' && s.Flag('+'):
+		_, _ = io.WriteString(s, err.Error())
+		writeLeaves(s, err.Errors(newOptions(nil)))
+	case verb == 'v' || verb == 's':
+		_, _ = io.WriteString(s, err.Error())
+	case verb == 'q':
+		_, _ = io.WriteString(s, strconv.Quote(err.Error()))
+	default:
+		_, _ = fmt.Fprintf(s, "%%!%c(%s)", verb, err.Error())
+	}
+}
+
+// formatError prints the whole tree at once, so it never returns the next error.
+func formatError(err treeNode, p xerrors.Printer) error {
+	p.Print(err.Error())
+	if p.Detail() {
+		writeLeaves(printerWriter{p: p}, err.Errors(newOptions(nil)))
+	}
+	return nil
+}
+
+func writeLeaves(w io.Writer, errs []errorWithFields) {
+	for i, leaf := range errs {
+		_, _ = fmt.Fprintf(w, "\nleaf %d: %s", i, leaf.Error())
+		_, _ = fmt.Fprintf(w, "\n    cause: %T", leaf.cause)
+		if len(leaf.path) > 0 {
+			_, _ = fmt.Fprintf(w, "\n    path: %s", strings.Join(leaf.path, " > "))
+		}
+		if len(leaf.fields) > 0 {
+			_, _ = io.WriteString(w, "\n    fields:")
+			for _, f := range leaf.fields.Flatten(".") {
+				_, _ = fmt.Fprintf(w, " %s=%v", f.Key, f.Value)
+			}
+		}
+		if leaf.stack != nil {
+			_, _ = io.WriteString(w, "\n    stack:")
+			for _, frame := range leaf.stack.Frames() {
+				_, _ = fmt.Fprintf(w, "\n        %s\n            %s:%d", frame.Function, frame.File, frame.Line)
+			}
+		}
+	}
+}
+
+type printerWriter struct {
+	p xerrors.Printer
+}
+
+func (w printerWriter) Write(b []byte) (int, error) {
+	w.p.Print(string(b))
+	return len(b), nil
+}
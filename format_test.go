@@ -0,0 +1,97 @@
+package errors_test
+
+import (
+	stderrors "errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/maratori/errors"
+)
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	single := errors.New("new err").WithField("key2", 2).Wrap("prefix1").Wrap("prefix2").WithField("key1", "value1").E()
+	joined := errors.Wrap("prefix", errors.Join(
+		errors.New("new err 1").WithField("key1", "value1").E(),
+		errors.Err(&customError{}).WithField("key2", "value2").E(),
+	)).WithField("key3", "value3").E()
+
+	t.Run("message", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, "prefix2: prefix1: new err", fmt.Sprintf("%v", single))
+		require.Equal(t, "prefix2: prefix1: new err", fmt.Sprintf("%s", single)) //nolint:staticcheck // test %s verb
+		require.Equal(t, `"prefix2: prefix1: new err"`, fmt.Sprintf("%q", single))
+		require.Equal(t, "prefix: new err 1\ncustom", fmt.Sprintf("%v", joined))
+		require.Equal(t, "%!d(prefix2: prefix1: new err)", fmt.Sprintf("%d", single))
+	})
+
+	t.Run("tree", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, `prefix2: prefix1: new err
+leaf 0: prefix2: prefix1: new err
+    cause: *errors.errorString
+    path: prefix2 > prefix1
+    fields: key2=2 key1=value1`, fmt.Sprintf("%+v", single))
+		require.Equal(t, `prefix: new err 1
+custom
+leaf 0: prefix: new err 1
+    cause: *errors.errorString
+    path: prefix
+    fields: key1=value1 key3=value3
+leaf 1: prefix: custom
+    cause: *errors_test.customError
+    path: prefix
+    fields: key2=value2 key3=value3`, fmt.Sprintf("%+v", joined))
+	})
+
+	t.Run("tree with stack", func(t *testing.T) {
+		t.Parallel()
+		err, line := errors.New("new err").WithStack().E(), currentLine()
+		frame := errors.StackFromError(err).Frames()[0]
+		formatted := fmt.Sprintf("%+v", err)
+		require.True(t, strings.HasPrefix(formatted, `new err
+leaf 0: new err
+    cause: *errors.errorString
+    stack:
+        `+frame.Function+`
+            `+frame.File+`:`+strconv.Itoa(line)+`
+`), formatted)
+	})
+
+	t.Run("go syntax", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, `errors.withFields{fields:errors.FieldList{errors.Field{Key:"key1", Value:"value1"}}, err:`+
+			`errors.withPrefix{prefix:"prefix2", err:errors.withPrefix{prefix:"prefix1", err:`+
+			`errors.withFields{fields:errors.FieldList{errors.Field{Key:"key2", Value:2}}, err:`+
+			`errors.wrapper{err:&errors.errorString{s:"new err"}}}}}}`,
+			fmt.Sprintf("%#v", single),
+		)
+		require.Equal(t, `errors.many{errors:[errors.wrapper{err:&errors.errorString{s:"new err 1"}}, `+
+			`errors.wrapper{err:&errors_test.customError{}}]}`,
+			fmt.Sprintf("%#v", errors.Join(stderrors.New("new err 1"), &customError{})),
+		)
+		err := errors.New("new err").WithStack().E()
+		require.Equal(t, fmt.Sprintf(
+			`errors.withStack{stack:%d frames, err:errors.wrapper{err:&errors.errorString{s:"new err"}}}`,
+			len(errors.StackFromError(err)),
+		), fmt.Sprintf("%#v", err))
+	})
+
+	t.Run("xerrors", func(t *testing.T) {
+		t.Parallel()
+		wrapped := xerrors.Errorf("context: %w", single)
+		require.Equal(t, "context: prefix2: prefix1: new err", fmt.Sprintf("%v", wrapped))
+		require.Contains(t, fmt.Sprintf("%+v", wrapped), "fields: key2=2 key1=value1")
+	})
+}
+
+type customError struct{}
+
+func (*customError) Error() string {
+	return "custom"
+}
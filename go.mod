@@ -2,7 +2,10 @@ module github.com/maratori/errors
 
 go 1.18 // minimal supported version 1.18, tested all versions up to 1.26
 
-require github.com/stretchr/testify v1.7.0
+require (
+	github.com/stretchr/testify v1.7.0
+	golang.org/x/xerrors v0.0.0-20240903120638-7835f813f4da
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
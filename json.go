@@ -0,0 +1,523 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// JSONVersion is the version of JSON format produced by MarshalJSON methods of errors.
+// See jsonschema/v1.json for the JSON Schema.
+const JSONVersion = 1
+
+const (
+	jsonLeaf    = "leaf"
+	jsonPrefix  = "prefix"
+	jsonFields  = "fields"
+	jsonMany    = "many"
+	jsonForeign = "foreign"
+)
+
+// jsonDocument is the root object.
+// Message, Fields and Leaves are the same as Error(), FieldsFromError() and Errors() return.
+// They are redundant and needed only for consumers that don't decode the Tree.
+type jsonDocument struct {
+	Version int            `json:"version"`
+	Message string         `json:"message"`
+	Fields  jsonObject     `json:"fields,omitempty"`
+	Leaves  []jsonLeafInfo `json:"leaves"`
+	Tree    *jsonNode      `json:"tree"`
+}
+
+type jsonLeafInfo struct {
+	Message string     `json:"message"`
+	Cause   string     `json:"cause"`
+	Path    []string   `json:"path,omitempty"`
+	Fields  jsonObject `json:"fields,omitempty"`
+}
+
+type jsonNode struct {
+	Type    string     `json:"type"`
+	Message string     `json:"message,omitempty"` // leaf, foreign
+	Prefix  string     `json:"prefix,omitempty"`  // prefix
+	Fields  jsonObject `json:"fields,omitempty"`  // fields
+	Groups  jsonGroups `json:"groups,omitempty"`  // fields
+	Error   *jsonNode  `json:"error,omitempty"`   // prefix, fields, foreign
+	Errors  []jsonNode `json:"errors,omitempty"`  // many
+}
+
+// jsonGroups marks which fields are groups (see Group), so they are decoded into FieldList and merged,
+// while other JSON objects (e.g. maps and structs) are decoded into map[string]any.
+// Each key is a group, its value contains nested groups.
+type jsonGroups map[string]jsonGroups
+
+// jsonObject is encoded as JSON object keeping order of fields.
+type jsonObject []jsonField
+
+type jsonField struct {
+	key   string
+	value json.RawMessage
+}
+
+//nolint:exhaustruct // false positive
+var (
+	_ json.Marshaler = wrapper{}
+	_ json.Marshaler = withPrefix{}
+	_ json.Marshaler = withFields{}
+	_ json.Marshaler = withStack{}
+	_ json.Marshaler = many{}
+)
+
+func (e wrapper) MarshalJSON() ([]byte, error)    { return marshalJSON(e) }
+func (e withPrefix) MarshalJSON() ([]byte, error) { return marshalJSON(e) }
+func (e withFields) MarshalJSON() ([]byte, error) { return marshalJSON(e) }
+func (e withStack) MarshalJSON() ([]byte, error)  { return marshalJSON(e) }
+func (e many) MarshalJSON() ([]byte, error)       { return marshalJSON(e) }
+
+// JSONError rebuilds error from JSON produced by MarshalJSON of an error built with this package:
+//
+//	var e errors.JSONError
+//	err := json.Unmarshal(data, &e)
+//	decoded := e.Err()
+//
+// Errors(), FieldsFromError() and Error() of the decoded error return the same as for the original one,
+// except that field values are normalized (see Normalize, e.g. errors become strings or groups)
+// and decoded by [encoding/json] into any (e.g. numbers become float64),
+// JSON objects are decoded into map[string]any unless they are groups (see Group)
+// and each leaf becomes an error created with [errors.New]. Foreign wrappers (e.g. created with [fmt.Errorf])
+// are decoded as errors with the same message, but without their types. Stacks are not encoded.
+// The zero value holds no error.
+type JSONError struct {
+	err treeNode
+}
+
+//nolint:exhaustruct // false positive
+var (
+	_ json.Marshaler   = JSONError{}
+	_ json.Unmarshaler = (*JSONError)(nil)
+)
+
+// Err returns the decoded error or nil if nothing was decoded.
+func (e JSONError) Err() error {
+	if e.err == nil {
+		return nil
+	}
+	return e.err
+}
+
+// MarshalJSON encodes the decoded error again, the zero value is encoded as JSON null.
+func (e JSONError) MarshalJSON() ([]byte, error) {
+	if e.err == nil {
+		return []byte("null"), nil
+	}
+	return marshalJSON(e.err)
+}
+
+// UnmarshalJSON decodes the error, e is left unchanged if decoding fails. JSON null resets e to the zero value.
+func (e *JSONError) UnmarshalJSON(data []byte) error {
+	if string(bytes.TrimSpace(data)) == "null" {
+		e.err = nil
+		return nil
+	}
+	var doc jsonDocument
+	err := json.Unmarshal(data, &doc)
+	if err != nil {
+		return Wrap("decode json", err).E()
+	}
+	if doc.Version != JSONVersion {
+		return New("unsupported json version").WithField("version", doc.Version).E()
+	}
+	if doc.Tree == nil {
+		return New("missing json tree").E()
+	}
+	res, err := decodeNode(*doc.Tree)
+	if err != nil {
+		return Wrap("decode json tree", err).E()
+	}
+	e.err = res
+	return nil
+}
+
+func marshalJSON(err treeNode) ([]byte, error) {
+	errs := err.Errors(newOptions(nil))
+	leaves := make([]jsonLeafInfo, 0, len(errs))
+	for _, leaf := range errs {
+		leaves = append(leaves, jsonLeafInfo{
+			Message: leaf.Error(),
+			Cause:   leaf.cause.Error(),
+			Path:    leaf.path,
+			Fields:  encodeFields(leaf.fields),
+		})
+	}
+	var fields FieldList
+	if len(errs) > 0 {
+		fields = errs[0].fields
+	}
+	tree := encodeNode(err)
+	return json.Marshal(jsonDocument{
+		Version: JSONVersion,
+		Message: err.Error(),
+		Fields:  encodeFields(fields),
+		Leaves:  leaves,
+		Tree:    &tree,
+	})
+}
+
+func encodeNode(err treeNode) jsonNode {
+	switch e := err.(type) {
+	case withPrefix:
+		child := encodeNode(e.err)
+		return jsonNode{
+			Type:    jsonPrefix,
+			Message: "",
+			Prefix:  e.prefix,
+			Fields:  nil,
+			Groups:  nil,
+			Error:   &child,
+			Errors:  nil,
+		}
+	case withFields:
+		child := encodeNode(e.err)
+		fields, _ := resolveFields(e.fields)
+		return jsonNode{
+			Type:    jsonFields,
+			Message: "",
+			Prefix:  "",
+			Fields:  encodeFields(fields),
+			Groups:  groupsOf(fields),
+			Error:   &child,
+			Errors:  nil,
+		}
+	case withStack:
+		return encodeNode(e.err)
+	case wrapper:
+		inner := foreignInner(e.err)
+		if inner == nil {
+			return encodeLeaves(e)
+		}
+		child := encodeNode(wrapper{err: inner})
+		if nodeMessage(child) != inner.Error() { // e.g. foreign multi-error with another separator
+			return encodeLeaves(e)
+		}
+		return jsonNode{
+			Type:    jsonForeign,
+			Message: e.err.Error(),
+			Prefix:  "",
+			Fields:  nil,
+			Groups:  nil,
+			Error:   &child,
+			Errors:  nil,
+		}
+	case many:
+		children := make([]jsonNode, 0, len(e.errors))
+		for _, child := range e.errors {
+			children = append(children, encodeNode(child))
+		}
+		return encodeMany(children)
+	default:
+		return encodeLeaves(err)
+	}
+}
+
+// encodeLeaves encodes err from its leaves, e.g. a foreign error or a leaf returned by Errors().
+func encodeLeaves(err treeNode) jsonNode {
+	errs := err.Errors(newOptions(nil))
+	children := make([]jsonNode, 0, len(errs))
+	for _, leaf := range errs {
+		children = append(children, encodeLeaf(leaf))
+	}
+	if len(children) == 1 {
+		return children[0]
+	}
+	return encodeMany(children)
+}
+
+// foreignInner returns error wrapped by foreign wrapper err if it's unwrapped by Errors (see unwrapForeign).
+// Such wrapper is encoded with its message, so the decoded error has the same message and leaves.
+func foreignInner(err error) error {
+	switch err.(type) { //nolint:errorlint // the same check as in wrapper.Errors
+	case treeNode, errorWithFields, Leaf, *Leaf:
+		return nil
+	}
+	if errs, ok := foreignErrors(err); ok && len(flattenForeign(errs, newOptions(nil))) > 0 {
+		return nil
+	}
+	if inner := errors.Unwrap(err); inner != nil && needsUnwrap(inner) {
+		return inner
+	}
+	return nil
+}
+
+// nodeMessage returns Error() of the error decoded from node.
+func nodeMessage(node jsonNode) string {
+	switch node.Type {
+	case jsonPrefix:
+		return node.Prefix + ": " + nodeMessage(*node.Error)
+	case jsonFields:
+		return nodeMessage(*node.Error)
+	case jsonMany:
+		msgs := make([]string, 0, len(node.Errors))
+		for _, child := range node.Errors {
+			msgs = append(msgs, nodeMessage(child))
+		}
+		return strings.Join(msgs, "\n")
+	default: // leaf, foreign
+		return node.Message
+	}
+}
+
+// foreignError is a decoded foreign wrapper.
+type foreignError struct {
+	msg string
+	err error
+}
+
+func (e foreignError) Error() string {
+	return e.msg
+}
+
+func (e foreignError) Unwrap() error {
+	return e.err
+}
+
+func encodeLeaf(leaf errorWithFields) jsonNode {
+	node := jsonNode{
+		Type:    jsonLeaf,
+		Message: leafMessage(leaf),
+		Prefix:  "",
+		Fields:  nil,
+		Groups:  nil,
+		Error:   nil,
+		Errors:  nil,
+	}
+	for i := len(leaf.path) - 1; i >= 0; i-- {
+		child := node
+		node = jsonNode{
+			Type:    jsonPrefix,
+			Message: "",
+			Prefix:  leaf.path[i],
+			Fields:  nil,
+			Groups:  nil,
+			Error:   &child,
+			Errors:  nil,
+		}
+	}
+	if len(leaf.fields) > 0 {
+		child := node
+		node = jsonNode{
+			Type:    jsonFields,
+			Message: "",
+			Prefix:  "",
+			Fields:  encodeFields(leaf.fields),
+			Groups:  groupsOf(leaf.fields),
+			Error:   &child,
+			Errors:  nil,
+		}
+	}
+	return node
+}
+
+// leafMessage returns message of the leaf without prefixes from its path. It differs from the message of the cause
+// if the leaf is wrapped by a foreign wrapper which message isn't of the form "prefix: <wrapped message>".
+func leafMessage(leaf errorWithFields) string {
+	msg := leaf.err.Error()
+	if len(leaf.path) == 0 {
+		return msg
+	}
+	prefix := strings.Join(leaf.path, ": ") + ": "
+	if !strings.HasPrefix(msg, prefix) {
+		return leaf.cause.Error()
+	}
+	return strings.TrimPrefix(msg, prefix)
+}
+
+func encodeMany(children []jsonNode) jsonNode {
+	return jsonNode{
+		Type:    jsonMany,
+		Message: "",
+		Prefix:  "",
+		Fields:  nil,
+		Groups:  nil,
+		Error:   nil,
+		Errors:  children,
+	}
+}
+
+// encodeFields encodes each value separately, so one unsupported value doesn't break the whole error.
+// Values are normalized (see Normalize) the same way as in logger adapters, so errors are encoded
+// as strings or groups. A value that still can't be encoded (e.g. NaN) is encoded as a string formatted with %v.
+func encodeFields(fields FieldList) jsonObject {
+	if len(fields) == 0 {
+		return nil
+	}
+	res := make(jsonObject, 0, len(fields))
+	for _, f := range fields {
+		value := Normalize(f.Value)
+		raw, err := json.Marshal(value)
+		if err != nil {
+			raw, _ = json.Marshal(fmt.Sprintf("%v", value)) //nolint:errcheck,errchkjson // string is always encoded
+		}
+		res = append(res, jsonField{
+			key:   f.Key,
+			value: raw,
+		})
+	}
+	return res
+}
+
+// groupsOf returns keys of groups in fields, nil if there are no groups.
+func groupsOf(fields FieldList) jsonGroups {
+	var res jsonGroups
+	for _, f := range fields {
+		group, ok := f.Value.(FieldList)
+		if !ok {
+			continue
+		}
+		if res == nil {
+			res = jsonGroups{}
+		}
+		nested := groupsOf(group)
+		if nested == nil {
+			nested = jsonGroups{} // encoded as {} instead of null
+		}
+		res[f.Key] = nested
+	}
+	return res
+}
+
+// MarshalJSON encodes fields as JSON object keeping their order, so groups are encoded as nested objects.
+func (l FieldList) MarshalJSON() ([]byte, error) {
+	return encodeFields(l).MarshalJSON()
+}
+
+func (f jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range f {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.key) //nolint:errcheck,errchkjson // string is always encoded
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(field.value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (f *jsonObject) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return New("fields must be json object").E()
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return Wrap("decode field key", err).E()
+		}
+		key, _ := tok.(string) // object key is always string
+		var value json.RawMessage
+		err = dec.Decode(&value)
+		if err != nil {
+			return Wrap("decode field value", err).WithField("key", key).E()
+		}
+		*f = append(*f, jsonField{
+			key:   key,
+			value: value,
+		})
+	}
+	return nil
+}
+
+func decodeNode(node jsonNode) (treeNode, error) {
+	switch node.Type {
+	case jsonLeaf:
+		return wrapper{
+			err: errors.New(node.Message),
+		}, nil
+	case jsonPrefix, jsonFields:
+		if node.Error == nil {
+			return nil, New("missing nested error").WithField("type", node.Type).E()
+		}
+		child, err := decodeNode(*node.Error)
+		if err != nil {
+			return nil, err
+		}
+		if node.Type == jsonPrefix {
+			return withPrefix{
+				err:    child,
+				prefix: node.Prefix,
+			}, nil
+		}
+		fields, err := decodeFields(node.Fields, node.Groups)
+		if err != nil {
+			return nil, err
+		}
+		return withFields{
+			err:    child,
+			fields: fields,
+			caller: nil,
+		}, nil
+	case jsonForeign:
+		if node.Error == nil {
+			return nil, New("missing nested error").WithField("type", node.Type).E()
+		}
+		child, err := decodeNode(*node.Error)
+		if err != nil {
+			return nil, err
+		}
+		return wrapper{
+			err: foreignError{
+				msg: node.Message,
+				err: child,
+			},
+		}, nil
+	case jsonMany:
+		children := make([]treeNode, 0, len(node.Errors))
+		for _, n := range node.Errors {
+			child, err := decodeNode(n)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, child)
+		}
+		if len(children) < 2 { //nolint:mnd // Join never produces many with less than 2 errors
+			return nil, New("too few errors in many").WithField("count", len(children)).E()
+		}
+		return many{
+			errors: children,
+		}, nil
+	default:
+		return nil, New("unknown node type").WithField("type", node.Type).E()
+	}
+}
+
+// decodeFields decodes fields marked as groups into FieldList keeping their order, so groups set by several wrappers
+// are merged the same way as in the original error. Other values are decoded by [encoding/json] into any.
+func decodeFields(raw jsonObject, groups jsonGroups) (FieldList, error) {
+	res := make([]Field, 0, len(raw))
+	for _, f := range raw {
+		var value any
+		if nested, ok := groups[f.key]; ok {
+			var group jsonObject
+			if err := group.UnmarshalJSON(f.value); err != nil {
+				return nil, Wrap("decode group", err).WithField("key", f.key).E()
+			}
+			fields, err := decodeFields(group, nested)
+			if err != nil {
+				return nil, err
+			}
+			value = fields
+		} else {
+			_ = json.Unmarshal(f.value, &value) //nolint:errcheck // raw message is already validated by json.Unmarshal
+		}
+		res = append(res, Field{
+			Key:   f.key,
+			Value: value,
+		})
+	}
+	return uniqueFields(res), nil
+}
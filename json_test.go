@@ -0,0 +1,333 @@
+package errors_test
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maratori/errors"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	t.Run("single error", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithField("key2", 2).Wrap("prefix").WithField("key1", "value1").E()
+		data, e := json.Marshal(err)
+		require.NoError(t, e)
+		require.JSONEq(t, `{
+			"version": 1,
+			"message": "prefix: new err",
+			"fields": {"key1": "value1", "key2": 2},
+			"leaves": [
+				{
+					"message": "prefix: new err",
+					"cause": "new err",
+					"path": ["prefix"],
+					"fields": {"key1": "value1", "key2": 2}
+				}
+			],
+			"tree": {"type": "fields", "fields": {"key1": "value1"}, "error": {
+				"type": "prefix", "prefix": "prefix", "error": {
+					"type": "fields", "fields": {"key2": 2}, "error": {
+						"type": "leaf", "message": "new err"
+					}
+				}
+			}}
+		}`, string(data))
+	})
+
+	t.Run("joined errors", func(t *testing.T) {
+		t.Parallel()
+		err := errors.Join(errors.New("new err 1").WithStack().E(), stderrors.New("new err 2"))
+		data, e := json.Marshal(err)
+		require.NoError(t, e)
+		require.JSONEq(t, `{
+			"version": 1,
+			"message": "new err 1\nnew err 2",
+			"leaves": [
+				{"message": "new err 1", "cause": "new err 1"},
+				{"message": "new err 2", "cause": "new err 2"}
+			],
+			"tree": {"type": "many", "errors": [
+				{"type": "leaf", "message": "new err 1"},
+				{"type": "leaf", "message": "new err 2"}
+			]}
+		}`, string(data))
+	})
+
+	t.Run("leaf returned by Errors", func(t *testing.T) {
+		t.Parallel()
+		original := errors.Wrap("prefix1", errors.Join(
+			errors.New("new err 1").E(),
+			errors.New("new err 2").Wrap("prefix2").WithField("key", "value").E(),
+		)).E()
+		err := errors.Err(errors.Errors(original)[1]).E()
+		data, e := json.Marshal(err)
+		require.NoError(t, e)
+		require.JSONEq(t, `{
+			"version": 1,
+			"message": "prefix1: prefix2: new err 2",
+			"fields": {"key": "value"},
+			"leaves": [
+				{
+					"message": "prefix1: prefix2: new err 2",
+					"cause": "new err 2",
+					"path": ["prefix1", "prefix2"],
+					"fields": {"key": "value"}
+				}
+			],
+			"tree": {"type": "fields", "fields": {"key": "value"}, "error": {
+				"type": "prefix", "prefix": "prefix1", "error": {
+					"type": "prefix", "prefix": "prefix2", "error": {
+						"type": "leaf", "message": "new err 2"
+					}
+				}
+			}}
+		}`, string(data))
+	})
+
+	t.Run("order of fields", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithField("key2", 2).WithField("key1", 1).E()
+		data, e := json.Marshal(err)
+		require.NoError(t, e)
+		require.Contains(t, string(data), `"fields":{"key2":2,"key1":1}`)
+
+		decoded := decode(t, data)
+		require.Equal(t, errors.FieldList{
+			{Key: "key2", Value: float64(2)},
+			{Key: "key1", Value: float64(1)},
+		}, errors.OrderedFields(decoded))
+	})
+
+	t.Run("unsupported field value", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithField("key", func() {}).E()
+		data, e := json.Marshal(err)
+		require.NoError(t, e)
+		require.Contains(t, string(data), `"fields":{"key":"0x`)
+	})
+}
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	t.Run("round trip", func(t *testing.T) {
+		t.Parallel()
+		for name, original := range map[string]error{
+			"new":     errors.New("new err").E(),
+			"foreign": stderrors.New("new err"),
+			"fields": errors.New("new err").
+				WithField("key2", 2.5).
+				Wrap("prefix").
+				WithField("key1", "value1").
+				E(),
+			"empty prefix and message": errors.Wrap("", errors.New("").E()).E(),
+			"foreign wrapper": errors.Wrap("prefix", fmt.Errorf("%w (ctx)", errors.New("new err").
+				WithField("key1", "value1").
+				E())).
+				E(),
+			"foreign prefix wrapper": fmt.Errorf("ctx: %w", errors.New("new err").WithField("key1", "value1").E()),
+			"foreign wrapper of joined": fmt.Errorf("%w (ctx)", errors.Join(
+				errors.New("new err 1").E(),
+				errors.Wrap("prefix", errors.New("new err 2").E()).E(),
+			)),
+			"groups": errors.New("new err").
+				WithGroup("db", errors.Field{Key: "table", Value: "orders"}).
+				Wrap("prefix").
+				WithGroup("db", errors.Field{Key: "query", Value: "select"}, errors.Group("args", errors.Field{
+					Key:   "id",
+					Value: "o1",
+				})).
+				E(),
+			"joined": errors.Wrap("prefix1", errors.Join(
+				errors.New("new err 1").WithField("key1", "value1").WithField("key2", "value2").E(),
+				errors.Join(
+					errors.Wrap("prefix2", stderrors.New("new err 2")).WithField("key1", "value3").E(),
+					errors.New("new err 3").E(),
+				),
+			)).WithField("key3", []any{"value4", true}).E(),
+		} {
+			original := original
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				data, err := json.Marshal(errors.Err(original).E())
+				require.NoError(t, err)
+				decoded := decode(t, data)
+
+				require.EqualError(t, decoded, original.Error())
+				require.Equal(t, errors.FieldsFromError(original), errors.FieldsFromError(decoded))
+				require.Equal(t, errors.OrderedFields(original), errors.OrderedFields(decoded))
+				originalLeaves := errors.Errors(original)
+				decodedLeaves := errors.Errors(decoded)
+				require.Len(t, decodedLeaves, len(originalLeaves))
+				for i := range originalLeaves {
+					require.EqualError(t, decodedLeaves[i], originalLeaves[i].Error())
+					originalFields := errors.FieldsFromError(originalLeaves[i])
+					require.Equal(t, originalFields, errors.FieldsFromError(decodedLeaves[i]))
+				}
+
+				encodedAgain, err := json.Marshal(decoded)
+				require.NoError(t, err)
+				require.JSONEq(t, string(data), string(encodedAgain))
+			})
+		}
+	})
+
+	t.Run("leaf of foreign wrapper", func(t *testing.T) {
+		t.Parallel()
+		leaf := errors.Errors(fmt.Errorf("%w (ctx)", errors.Wrap("prefix", errors.New("new err 1").E()).E()))[0]
+		original := errors.Join(leaf, errors.New("new err 2").E())
+		data, err := json.Marshal(original)
+		require.NoError(t, err)
+		decoded := decode(t, data)
+		require.EqualError(t, decoded, "prefix: new err 1 (ctx)\nnew err 2")
+		leaves := errors.Leaves(decoded)
+		require.Len(t, leaves, 2)
+		require.Equal(t, []string{"prefix"}, leaves[0].Path)
+		require.Equal(t, "new err 1 (ctx)", leaves[0].Message) // the cause can't be separated from the context
+	})
+
+	t.Run("error values", func(t *testing.T) {
+		t.Parallel()
+		original := errors.New("new err").
+			WithField("cause", stderrors.New("boom")).
+			WithField("inner", errors.New("inner").WithField("id", "i1").E()).
+			E()
+		data, err := json.Marshal(original)
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"fields":{"cause":"boom","inner":{"message":"inner","id":"i1"}}`)
+
+		decoded := decode(t, data)
+		require.Equal(t, errors.FieldList{
+			{Key: "cause", Value: "boom"},
+			{Key: "inner", Value: map[string]any{"message": "inner", "id": "i1"}},
+		}, errors.OrderedFields(decoded))
+	})
+
+	t.Run("map is not a group", func(t *testing.T) {
+		t.Parallel()
+		original := errors.New("new err").
+			WithField("m", map[string]string{"a": "1"}).
+			WithGroup("g", errors.Field{Key: "m", Value: map[string]string{"c": "3"}}).
+			Wrap("prefix").
+			WithField("m", map[string]string{"b": "2"}).
+			WithGroup("g", errors.Field{Key: "n", Value: "4"}).
+			E()
+		data, err := json.Marshal(original)
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"groups":{"g":{}}`)
+
+		decoded := decode(t, data)
+		require.Equal(t, errors.FieldList{
+			{Key: "m", Value: map[string]any{"a": "1"}},
+			{Key: "g", Value: errors.FieldList{
+				{Key: "m", Value: map[string]any{"c": "3"}},
+				{Key: "n", Value: "4"},
+			}},
+		}, errors.OrderedFields(decoded))
+		require.Equal(t, errors.NormalizeFields(errors.OrderedFields(original)),
+			errors.NormalizeFields(errors.OrderedFields(decoded)))
+	})
+
+	t.Run("null", func(t *testing.T) {
+		t.Parallel()
+		var decoded errors.JSONError
+		require.NoError(t, json.Unmarshal([]byte(`{"version":1,"tree":{"type":"leaf","message":"new err"}}`), &decoded))
+		require.EqualError(t, decoded.Err(), "new err")
+		require.NoError(t, json.Unmarshal([]byte(`null`), &decoded))
+		require.NoError(t, decoded.Err())
+		data, err := json.Marshal(decoded)
+		require.NoError(t, err)
+		require.Equal(t, "null", string(data))
+	})
+
+	t.Run("struct field", func(t *testing.T) {
+		t.Parallel()
+		original := errors.New("new err").WithField("key", "value").E()
+		data, err := json.Marshal(map[string]any{"err": original})
+		require.NoError(t, err)
+		var decoded struct {
+			Err errors.JSONError `json:"err"`
+		}
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		require.EqualError(t, decoded.Err.Err(), "new err")
+		require.Equal(t, errors.Fields{"key": "value"}, errors.FieldsFromError(decoded.Err.Err()))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		for name, tc := range map[string]struct {
+			data string
+			err  string
+		}{
+			"not json": {
+				data: `{`,
+				err:  "unexpected end of JSON input",
+			},
+			"wrong version": {
+				data: `{"version": 2}`,
+				err:  "unsupported json version",
+			},
+			"missing tree": {
+				data: `{"version": 1}`,
+				err:  "missing json tree",
+			},
+			"unknown type": {
+				data: `{"version": 1, "tree": {"type": "x"}}`,
+				err:  "decode json tree: unknown node type",
+			},
+			"missing nested": {
+				data: `{"version": 1, "tree": {"type": "prefix"}}`,
+				err:  "decode json tree: missing nested error",
+			},
+			"missing nested in foreign": {
+				data: `{"version": 1, "tree": {"type": "foreign", "message": "msg"}}`,
+				err:  "decode json tree: missing nested error",
+			},
+			"too few in many": {
+				data: `{"version": 1, "tree": {"type": "many", "errors": [{"type": "leaf"}]}}`,
+				err:  "decode json tree: too few errors in many",
+			},
+			"invalid in many": {
+				data: `{"version": 1, "tree": {"type": "many", "errors": [{"type": "x"}]}}`,
+				err:  "decode json tree: unknown node type",
+			},
+			"fields not object": {
+				data: `{"version": 1, "tree": {"type": "fields", "fields": 1}}`,
+				err:  "decode json: fields must be json object",
+			},
+			"group not object": {
+				data: `{"version": 1, "tree": {
+					"type": "fields", "fields": {"g": 1}, "groups": {"g": {}}, "error": {"type": "leaf"}
+				}}`,
+				err: "decode json tree: decode group: fields must be json object",
+			},
+			"invalid in fields": {
+				data: `{"version": 1, "tree": {"type": "fields", "error": {"type": "x"}}}`,
+				err:  "decode json tree: unknown node type",
+			},
+		} {
+			tc := tc
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				var decoded errors.JSONError
+				err := json.Unmarshal([]byte(tc.data), &decoded)
+				require.EqualError(t, err, tc.err)
+				require.NoError(t, decoded.Err())
+			})
+		}
+	})
+}
+
+func decode(t *testing.T, data []byte) error {
+	t.Helper()
+	var decoded errors.JSONError
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	return decoded.Err()
+}
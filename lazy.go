@@ -0,0 +1,67 @@
+package errors
+
+import (
+	"sync"
+)
+
+// Lazy returns value of a field which is computed by f only when fields are extracted,
+// e.g. by FieldsFromError, Errors or logger adapters. It's useful for expensive values,
+// because an error may be handled without logging. f is called at most once, so all leaves
+// and all extractions share the same value, e.g.
+//
+//	errors.WithField(err, "request", errors.Lazy(func() any { return dump(req) }))
+func Lazy(f func() any) any {
+	return &lazyValue{
+		once:  sync.Once{},
+		f:     f,
+		value: nil,
+	}
+}
+
+type lazyValue struct {
+	once  sync.Once
+	f     func() any
+	value any
+}
+
+func (v *lazyValue) resolve() any {
+	v.once.Do(func() {
+		v.value = v.f()
+		v.f = nil // release captured variables
+	})
+	return v.value
+}
+
+// resolveFields returns fields with computed lazy values, including values in groups.
+// Fields are returned as is if there are no lazy values.
+func resolveFields(fields FieldList) (FieldList, bool) {
+	var res FieldList // allocated only if there is a lazy value
+	for i, f := range fields {
+		value, resolved := resolveValue(f.Value)
+		if !resolved && res == nil {
+			continue
+		}
+		if res == nil {
+			res = append(make(FieldList, 0, len(fields)), fields[:i]...)
+		}
+		res = append(res, Field{
+			Key:   f.Key,
+			Value: value,
+		})
+	}
+	if res == nil {
+		return fields, false
+	}
+	return res, true
+}
+
+func resolveValue(value any) (any, bool) {
+	switch v := value.(type) {
+	case *lazyValue:
+		return v.resolve(), true
+	case FieldList:
+		return resolveFields(v)
+	default:
+		return value, false
+	}
+}
@@ -0,0 +1,96 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maratori/errors"
+)
+
+func TestLazy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("computed only on extraction", func(t *testing.T) {
+		t.Parallel()
+		var calls int32
+		err := errors.New("new err").WithField("key", errors.Lazy(func() any {
+			atomic.AddInt32(&calls, 1)
+			return "value"
+		})).Wrap("prefix").E()
+		require.Equal(t, "prefix: new err", err.Error())
+		require.Zero(t, atomic.LoadInt32(&calls))
+		require.Equal(t, errors.Fields{"key": "value"}, errors.FieldsFromError(err))
+		require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("computed once for all leaves and extractions", func(t *testing.T) {
+		t.Parallel()
+		var calls int32
+		err := errors.Join(errors.New("err1").E(), errors.New("err2").E())
+		err = errors.WithFields(err, errors.Fields{"key": errors.Lazy(func() any {
+			return atomic.AddInt32(&calls, 1)
+		})}).E()
+		require.Equal(t, []errors.Fields{{"key": int32(1)}, {"key": int32(1)}}, errors.LeafFields(err))
+		require.Equal(t, errors.FieldList{{Key: "key", Value: int32(1)}}, errors.Leaves(err)[1].Fields)
+		require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("concurrent extraction", func(t *testing.T) {
+		t.Parallel()
+		var calls int32
+		err := errors.New("new err").With("key", errors.Lazy(func() any {
+			return atomic.AddInt32(&calls, 1)
+		})).E()
+		values := make([]any, 10)
+		var wg sync.WaitGroup
+		for i := range values {
+			i := i
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				values[i] = errors.FieldsFromError(err)["key"]
+			}()
+		}
+		wg.Wait()
+		for _, value := range values {
+			require.Equal(t, int32(1), value)
+		}
+		require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("value in group", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").
+			WithGroup("db", errors.Field{Key: "table", Value: "orders"}).
+			WithGroup("db", errors.Field{Key: "query", Value: errors.Lazy(func() any { return "select" })}).
+			E()
+		require.Equal(t, errors.FieldList{
+			{Key: "db", Value: errors.FieldList{{Key: "table", Value: "orders"}, {Key: "query", Value: "select"}}},
+		}, errors.OrderedFields(err))
+	})
+
+	t.Run("group value", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").
+			WithGroup("db", errors.Field{Key: "table", Value: "orders"}).
+			WithField("db", errors.Lazy(func() any {
+				return errors.FieldList{{Key: "query", Value: "select"}}
+			})).
+			E()
+		require.Equal(t, errors.FieldList{
+			{Key: "db", Value: errors.FieldList{{Key: "table", Value: "orders"}, {Key: "query", Value: "select"}}},
+		}, errors.OrderedFields(err))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithField("key", errors.Lazy(func() any { return 1 })).E()
+		data, e := json.Marshal(err)
+		require.NoError(t, e)
+		require.Contains(t, string(data), `"fields":{"key":1}`)
+	})
+}
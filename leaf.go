@@ -0,0 +1,96 @@
+package errors
+
+import (
+	"errors"
+	"strings"
+)
+
+// Leaf is a leaf of the errors tree with prefixes and fields collected from all its wrappers.
+// Leaf (or pointer to Leaf) can be passed to Err, Wrap, WithFields, WithField and Join without losing its fields.
+type Leaf struct {
+	// Message is the message of Cause without prefixes.
+	Message string
+	// Path contains prefixes from the outermost to the innermost.
+	Path []string
+	// Fields are merged from all wrappers according to the conflict policy.
+	Fields FieldList
+	// Cause is the original leaf error.
+	Cause error
+	// Stack is nil if it wasn't captured.
+	Stack Stack
+}
+
+// Leaves returns leaves of the errors tree in the same order as Errors.
+func Leaves(err error, opts ...Option) []Leaf {
+	errs := leavesOf(err, opts)
+	res := make([]Leaf, 0, len(errs))
+	for _, leaf := range errs {
+		res = append(res, leaf.leaf())
+	}
+	return res
+}
+
+// Error returns prefixes from Path and Message joined with ": " the same way as Errors does.
+// The only difference is a message of a foreign wrapper which isn't of the form "prefix: <wrapped message>",
+// Errors keeps it, but it's not a part of Path.
+func (l Leaf) Error() string {
+	if len(l.Path) == 0 {
+		return l.Message
+	}
+	return strings.Join(l.Path, ": ") + ": " + l.Message
+}
+
+func (l Leaf) Unwrap() error {
+	return l.Cause
+}
+
+// RangeLeaves calls f for each leaf returned by Leaves without copying Path and Fields of the leaf,
+// so f must not modify them. If f returns false, RangeLeaves stops the iteration.
+func RangeLeaves(err error, f func(leaf Leaf) bool, opts ...Option) {
+	for _, leaf := range leavesOf(err, opts) {
+		if !f(leaf.view()) {
+			return
+		}
+	}
+}
+
+func (e errorWithFields) leaf() Leaf {
+	res := e.view()
+	res.Path = append([]string(nil), res.Path...)
+	res.Fields = res.Fields.clone()
+	return res
+}
+
+// view returns leaf sharing path and fields with e.
+func (e errorWithFields) view() Leaf {
+	var stack Stack
+	if e.stack != nil {
+		stack = *e.stack
+	}
+	return Leaf{
+		Message: e.cause.Error(),
+		Path:    e.path,
+		Fields:  e.fields,
+		Cause:   e.cause,
+		Stack:   stack,
+	}
+}
+
+func (l Leaf) errorWithFields() errorWithFields {
+	var stack *Stack
+	if l.Stack != nil {
+		stack = &l.Stack
+	}
+	cause := l.Cause
+	if cause == nil {
+		cause = errors.New(l.Message)
+	}
+	return errorWithFields{
+		err:     l,
+		fields:  l.Fields,
+		stack:   stack,
+		cause:   cause,
+		path:    l.Path,
+		origins: nil,
+	}
+}
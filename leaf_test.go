@@ -0,0 +1,161 @@
+package errors_test
+
+import (
+	stderrors "errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maratori/errors"
+)
+
+func TestLeaves(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil error", func(t *testing.T) {
+		t.Parallel()
+		require.Empty(t, errors.Leaves(nil))
+	})
+
+	t.Run("foreign error", func(t *testing.T) {
+		t.Parallel()
+		original := stderrors.New("new err")
+		require.Equal(t, []errors.Leaf{{
+			Message: "new err",
+			Path:    nil,
+			Fields:  nil,
+			Cause:   original,
+			Stack:   nil,
+		}}, errors.Leaves(original))
+	})
+
+	t.Run("joined errors", func(t *testing.T) {
+		t.Parallel()
+		original1 := stderrors.New("new err 1")
+		original2 := stderrors.New("new err 2")
+		err := errors.Wrap("prefix1", errors.Join(
+			errors.Err(original1).WithField("key1", "value1").E(),
+			errors.Wrap("prefix2", original2).WithField("key2", "value2").E(),
+		)).WithField("key3", "value3").E()
+
+		leaves := errors.Leaves(err)
+		require.Equal(t, []errors.Leaf{
+			{
+				Message: "new err 1",
+				Path:    []string{"prefix1"},
+				Fields: errors.FieldList{
+					{Key: "key1", Value: "value1"},
+					{Key: "key3", Value: "value3"},
+				},
+				Cause: original1,
+				Stack: nil,
+			},
+			{
+				Message: "new err 2",
+				Path:    []string{"prefix1", "prefix2"},
+				Fields: errors.FieldList{
+					{Key: "key2", Value: "value2"},
+					{Key: "key3", Value: "value3"},
+				},
+				Cause: original2,
+				Stack: nil,
+			},
+		}, leaves)
+
+		errs := errors.Errors(err)
+		require.Len(t, errs, len(leaves))
+		for i := range leaves {
+			require.EqualError(t, leaves[i], errs[i].Error())
+		}
+		require.ErrorIs(t, leaves[1], original2)
+	})
+
+	t.Run("stack", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithStack().E()
+		leaves := errors.Leaves(err)
+		require.Len(t, leaves, 1)
+		require.Equal(t, errors.StackFromError(err), leaves[0].Stack)
+	})
+
+	t.Run("leaf keeps fields", func(t *testing.T) {
+		t.Parallel()
+		leaf := errors.Leaves(errors.Wrap("prefix1", errors.New("new err").WithField("key1", "value1").E()).E())[0]
+		err := errors.Wrap("prefix2", leaf).WithField("key2", "value2").E()
+		require.EqualError(t, err, "prefix2: prefix1: new err")
+		require.Equal(t, errors.Fields{"key1": "value1", "key2": "value2"}, errors.FieldsFromError(err))
+
+		leaves := errors.Leaves(err)
+		require.Len(t, leaves, 1)
+		require.Equal(t, []string{"prefix2", "prefix1"}, leaves[0].Path)
+		require.Equal(t, "new err", leaves[0].Message)
+	})
+
+	t.Run("pointer to leaf keeps fields", func(t *testing.T) {
+		t.Parallel()
+		leaves := errors.Leaves(errors.Wrap("prefix1", errors.New("new err").WithField("key1", "value1").E()).E())
+		leaf := &leaves[0]
+		require.Equal(t, errors.Fields{"key1": "value1"}, errors.FieldsFromError(leaf))
+
+		err := fmt.Errorf("foreign: %w", errors.Join(leaf, stderrors.New("another err")))
+		require.Equal(t, errors.Fields{"key1": "value1"}, errors.FieldsFromError(err))
+		require.Equal(t, []string{"foreign", "prefix1"}, errors.Leaves(err)[0].Path)
+	})
+
+	t.Run("leaf without cause", func(t *testing.T) {
+		t.Parallel()
+		leaf := errors.Leaf{
+			Message: "new err",
+			Path:    []string{"prefix"},
+			Fields:  nil,
+			Cause:   nil,
+			Stack:   nil,
+		}
+		require.EqualError(t, leaf, "prefix: new err")
+		require.NoError(t, stderrors.Unwrap(leaf))
+
+		leaves := errors.Leaves(leaf)
+		require.Len(t, leaves, 1)
+		require.Equal(t, "new err", leaves[0].Message)
+		require.Equal(t, []string{"prefix"}, leaves[0].Path)
+		require.EqualError(t, leaves[0].Cause, "new err")
+	})
+}
+
+func TestRangeLeaves(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil error", func(t *testing.T) {
+		t.Parallel()
+		errors.RangeLeaves(nil, func(errors.Leaf) bool {
+			require.Fail(t, "must not be called")
+			return true
+		})
+	})
+
+	t.Run("same leaves as Leaves", func(t *testing.T) {
+		t.Parallel()
+		err := errors.Wrap("prefix", errors.Join(
+			errors.New("new err 1").WithField("key1", "value1").E(),
+			errors.Wrap("prefix2", stderrors.New("new err 2")).WithField("key2", "value2").E(),
+		)).WithField("key3", "value3").E()
+		var leaves []errors.Leaf
+		errors.RangeLeaves(err, func(leaf errors.Leaf) bool {
+			leaves = append(leaves, leaf)
+			return true
+		})
+		require.Equal(t, errors.Leaves(err), leaves)
+	})
+
+	t.Run("stop iteration", func(t *testing.T) {
+		t.Parallel()
+		err := errors.Join(errors.New("new err 1").E(), errors.New("new err 2").E())
+		calls := 0
+		errors.RangeLeaves(err, func(errors.Leaf) bool {
+			calls++
+			return false
+		})
+		require.Equal(t, 1, calls)
+	})
+}
@@ -0,0 +1,206 @@
+// Package logrerrors converts errors with fields into key/value pairs for [logr.Logger].
+package logrerrors
+
+import (
+	"github.com/go-logr/logr"
+
+	"github.com/maratori/errors"
+)
+
+const (
+	messageKey   = "message"
+	pathKey      = "path"
+	errorPathKey = "error.path"
+	leavesKey    = "leaves"
+)
+
+// Option configures how errors are converted.
+type Option func(*options)
+
+type options struct {
+	path           bool
+	groupSeparator string
+}
+
+// WithPath adds prefixes of the first leaf as an ordered list under "error.path" key.
+// Each leaf in "leaves" has the constant message without prefixes and its own "path".
+// Logger and LogSink also pass the cause of the first leaf to [logr.LogSink.Error] instead of the error,
+// so the logged error message is constant.
+func WithPath() Option {
+	return func(o *options) {
+		o.path = true
+	}
+}
+
+// WithGroupSeparator flattens groups of fields (see [errors.Group]) into keys joined with sep,
+// e.g. "db.table" for ".". By default, groups are converted into nested maps.
+func WithGroupSeparator(sep string) Option {
+	return func(o *options) {
+		o.groupSeparator = sep
+	}
+}
+
+func newOptions(opts []Option) options {
+	res := options{
+		path:           false,
+		groupSeparator: "",
+	}
+	for _, opt := range opts {
+		opt(&res)
+	}
+	return res
+}
+
+// KeysAndValues returns fields from [errors.OrderedFields] as key/value pairs.
+// If there is more than one leaf, a list of leaves from [errors.Errors] is added under "leaves" key.
+// Each leaf is a map with its message and fields.
+// Fields colliding with keys added next to them ("error.path" and "leaves" at the top level,
+// "message" and "path" in a leaf) are renamed with [errors.FieldList.RenameReserved].
+func KeysAndValues(err error, opts ...Option) []any {
+	return newOptions(opts).keysAndValues(err)
+}
+
+func (o options) keysAndValues(err error) []any {
+	if err == nil {
+		return nil
+	}
+	all := errors.Leaves(err)
+	fields := o.fields(all[0]).RenameReserved(errorPathKey, leavesKey)
+	res := make([]any, 0, 2*len(fields)+4) //nolint:mnd // key and value of each field, path and leaves
+	if o.path && len(all[0].Path) > 0 {
+		res = append(res, errorPathKey, all[0].Path)
+	}
+	for _, f := range fields {
+		res = append(res, f.Key, value(f.Value))
+	}
+	if len(all) > 1 {
+		leaves := make([]map[string]any, 0, len(all))
+		for _, leaf := range all {
+			m := toMap(o.fields(leaf).RenameReserved(messageKey, pathKey))
+			if o.path {
+				m[messageKey] = leaf.Message
+				if len(leaf.Path) > 0 {
+					m[pathKey] = leaf.Path
+				}
+			} else {
+				m[messageKey] = leaf.Error()
+			}
+			leaves = append(leaves, m)
+		}
+		res = append(res, leavesKey, leaves)
+	}
+	return res
+}
+
+func (o options) fields(leaf errors.Leaf) errors.FieldList {
+	fields := errors.NormalizeFields(leaf.Fields)
+	if o.groupSeparator != "" {
+		return fields.Flatten(o.groupSeparator)
+	}
+	return fields
+}
+
+// value converts groups into nested maps, including groups in lists.
+func value(v any) any {
+	switch v := v.(type) {
+	case errors.FieldList:
+		return toMap(v)
+	case []any:
+		res := make([]any, 0, len(v))
+		for _, elem := range v {
+			res = append(res, value(elem))
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+func toMap(fields errors.FieldList) map[string]any {
+	res := make(map[string]any, len(fields))
+	for _, f := range fields {
+		res[f.Key] = value(f.Value)
+	}
+	return res
+}
+
+// Logger returns a copy of logger which adds KeysAndValues of the error passed to [logr.Logger.Error].
+func Logger(logger logr.Logger, opts ...Option) logr.Logger {
+	next := logger.GetSink()
+	if next == nil { // discard logger
+		return logger
+	}
+	// The sink is already initialized, so it's necessary to skip frame of the wrapper here.
+	if withDepth, ok := next.(logr.CallDepthLogSink); ok {
+		next = withDepth.WithCallDepth(1)
+	}
+	return logger.WithSink(&sink{
+		next: next,
+		opts: newOptions(opts),
+	})
+}
+
+// NewLogSink returns [logr.LogSink] which adds KeysAndValues of the error passed to Error method.
+// It's intended to be used with [logr.New], use Logger to wrap existing logger.
+func NewLogSink(next logr.LogSink, opts ...Option) logr.LogSink {
+	return &sink{
+		next: next,
+		opts: newOptions(opts),
+	}
+}
+
+type sink struct {
+	next logr.LogSink
+	opts options
+}
+
+var _ logr.CallDepthLogSink = (*sink)(nil)
+
+func (s *sink) Init(info logr.RuntimeInfo) {
+	info.CallDepth++ // skip frame of the wrapper
+	s.next.Init(info)
+}
+
+func (s *sink) Enabled(level int) bool {
+	return s.next.Enabled(level)
+}
+
+func (s *sink) Info(level int, msg string, keysAndValues ...any) {
+	s.next.Info(level, msg, keysAndValues...)
+}
+
+func (s *sink) Error(err error, msg string, keysAndValues ...any) {
+	extra := s.opts.keysAndValues(err)
+	kv := make([]any, 0, len(keysAndValues)+len(extra)) // don't append to caller's slice
+	kv = append(kv, keysAndValues...)
+	kv = append(kv, extra...)
+	if s.opts.path && err != nil {
+		err = errors.Leaves(err)[0].Cause
+	}
+	s.next.Error(err, msg, kv...)
+}
+
+func (s *sink) WithValues(keysAndValues ...any) logr.LogSink {
+	return &sink{
+		next: s.next.WithValues(keysAndValues...),
+		opts: s.opts,
+	}
+}
+
+func (s *sink) WithName(name string) logr.LogSink {
+	return &sink{
+		next: s.next.WithName(name),
+		opts: s.opts,
+	}
+}
+
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	next, ok := s.next.(logr.CallDepthLogSink)
+	if !ok {
+		return s
+	}
+	return &sink{
+		next: next.WithCallDepth(depth),
+		opts: s.opts,
+	}
+}
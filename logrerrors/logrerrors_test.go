@@ -0,0 +1,262 @@
+package logrerrors_test
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maratori/errors"
+	"github.com/maratori/errors/logrerrors"
+)
+
+func TestKeysAndValues(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil error", func(t *testing.T) {
+		t.Parallel()
+		require.Nil(t, logrerrors.KeysAndValues(nil))
+	})
+
+	t.Run("foreign error", func(t *testing.T) {
+		t.Parallel()
+		require.Empty(t, logrerrors.KeysAndValues(stderrors.New("new err")))
+	})
+
+	t.Run("ordered fields", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithField("key2", 2).Wrap("prefix").WithField("key1", "value1").E()
+		require.Equal(t, []any{"key2", int64(2), "key1", "value1"}, logrerrors.KeysAndValues(err))
+	})
+
+	t.Run("reserved keys", func(t *testing.T) {
+		t.Parallel()
+		err := errors.Join(
+			errors.New("new err 1").With("message", "field", "error.path", 1).E(),
+			errors.New("new err 2").With("leaves", 2).E(),
+		)
+		require.Equal(t, []any{
+			"message", "field",
+			"error.path#1", int64(1),
+			"leaves", []map[string]any{
+				{"message": "new err 1", "message#1": "field", "error.path": int64(1)},
+				{"message": "new err 2", "leaves": int64(2)},
+			},
+		}, logrerrors.KeysAndValues(err))
+	})
+
+	t.Run("joined errors", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, []any{
+			"key1", "value1",
+			"key3", "value3",
+			"leaves", []map[string]any{
+				{"message": "prefix: new err 1", "key1": "value1", "key3": "value3"},
+				{"message": "prefix: new err 2", "key2": "value2", "key3": "value3"},
+			},
+		}, logrerrors.KeysAndValues(joined()))
+	})
+}
+
+func TestPath(t *testing.T) {
+	t.Parallel()
+	err := errors.Wrap("prefix1", errors.Join(
+		errors.New("new err 1").Wrap("prefix2").WithField("key1", "value1").E(),
+		stderrors.New("new err 2"),
+	)).E()
+
+	t.Run("keys and values", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, []any{
+			"error.path", []string{"prefix1", "prefix2"},
+			"key1", "value1",
+			"leaves", []map[string]any{
+				{"message": "new err 1", "path": []string{"prefix1", "prefix2"}, "key1": "value1"},
+				{"message": "new err 2", "path": []string{"prefix1"}},
+			},
+		}, logrerrors.KeysAndValues(err, logrerrors.WithPath()))
+	})
+
+	t.Run("logger", func(t *testing.T) {
+		t.Parallel()
+		logger, lines := newLogger()
+		logrerrors.Logger(logger, logrerrors.WithPath()).Error(err, "failed")
+		require.Len(t, *lines, 1)
+		require.JSONEq(t, `{
+			"caller":{"file":"logrerrors_test.go"},
+			"logger":"",
+			"msg":"failed",
+			"error":"new err 1",
+			"error.path":["prefix1","prefix2"],
+			"key1":"value1",
+			"leaves":[
+				{"message":"new err 1","path":["prefix1","prefix2"],"key1":"value1"},
+				{"message":"new err 2","path":["prefix1"]}
+			]
+		}`, withoutLine(t, (*lines)[0]))
+	})
+}
+
+func TestGroups(t *testing.T) {
+	t.Parallel()
+	err := errors.Join(
+		errors.New("new err 1").WithGroup("db", errors.Field{Key: "table", Value: "orders"}).E(),
+		errors.New("new err 2").E(),
+	)
+
+	t.Run("nested", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, []any{
+			"db", map[string]any{"table": "orders"},
+			"leaves", []map[string]any{
+				{"message": "new err 1", "db": map[string]any{"table": "orders"}},
+				{"message": "new err 2"},
+			},
+		}, logrerrors.KeysAndValues(err))
+	})
+
+	t.Run("flatten", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, []any{
+			"db.table", "orders",
+			"leaves", []map[string]any{
+				{"message": "new err 1", "db.table": "orders"},
+				{"message": "new err 2"},
+			},
+		}, logrerrors.KeysAndValues(err, logrerrors.WithGroupSeparator(".")))
+	})
+}
+
+func TestNormalizedValues(t *testing.T) {
+	t.Parallel()
+	err := errors.New("new err").
+		With("cause", errors.New("inner").WithField("id", 1).E(), "raw", []byte("bytes"), "ids", []uint{1, 2}).
+		E()
+	require.Equal(t, []any{
+		"cause", map[string]any{"message": "inner", "id": int64(1)},
+		"raw", "bytes",
+		"ids", []any{int64(1), int64(2)},
+	}, logrerrors.KeysAndValues(err))
+}
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+
+	t.Run("discard", func(t *testing.T) {
+		t.Parallel()
+		logger := logrerrors.Logger(logr.Discard())
+		logger.Error(joined(), "failed")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		logger, lines := newLogger()
+		logger = logrerrors.Logger(logger).WithName("name").WithValues("key", "value")
+		logger.Error(joined(), "failed", "other", 1)
+		logger.Info("ok")
+		require.Len(t, *lines, 2)
+		require.JSONEq(t, `{
+			"caller":{"file":"logrerrors_test.go"},
+			"logger":"name",
+			"msg":"failed",
+			"error":"prefix: new err 1\nnew err 2",
+			"key":"value",
+			"other":1,
+			"key1":"value1",
+			"key3":"value3",
+			"leaves":[
+				{"message":"prefix: new err 1","key1":"value1","key3":"value3"},
+				{"message":"prefix: new err 2","key2":"value2","key3":"value3"}
+			]
+		}`, withoutLine(t, (*lines)[0]))
+		require.JSONEq(t, `{
+			"caller":{"file":"logrerrors_test.go"},
+			"logger":"name",
+			"level":0,
+			"msg":"ok",
+			"key":"value"
+		}`, withoutLine(t, (*lines)[1]))
+	})
+
+	t.Run("call depth", func(t *testing.T) {
+		t.Parallel()
+		logger, lines := newLogger()
+		logrerrors.Logger(logger).WithCallDepth(1).Error(stderrors.New("new err"), "failed")
+		require.Len(t, *lines, 1)
+		require.Contains(t, (*lines)[0], `"file":"testing.go"`)
+	})
+}
+
+func TestNewLogSink(t *testing.T) {
+	t.Parallel()
+	sink := &recordingSink{
+		callDepth:     0,
+		keysAndValues: nil,
+	}
+	logger := logr.New(logrerrors.NewLogSink(sink, logrerrors.WithPath()))
+	require.Equal(t, 2, sink.callDepth) // logr.Logger and wrapper frames
+
+	logger.Error(errors.New("new err").WithField("key", "value").E(), "failed", "other", 1)
+	require.Equal(t, []any{"other", 1, "key", "value"}, sink.keysAndValues)
+
+	logger.WithCallDepth(1).Error(stderrors.New("new err"), "failed")
+	require.Empty(t, sink.keysAndValues)
+}
+
+func joined() error {
+	return errors.Wrap("prefix", errors.Join(
+		errors.New("new err 1").WithField("key1", "value1").E(),
+		errors.New("new err 2").WithField("key2", "value2").E(),
+	)).WithField("key3", "value3").E()
+}
+
+func newLogger() (logr.Logger, *[]string) {
+	var lines []string
+	logger := funcr.NewJSON(func(obj string) {
+		lines = append(lines, obj)
+	}, funcr.Options{LogCaller: funcr.All}) //nolint:exhaustruct // only caller is needed
+	return logger, &lines
+}
+
+// withoutLine removes caller line number to keep tests stable.
+func withoutLine(t *testing.T, line string) string {
+	t.Helper()
+	var obj map[string]any
+	require.NoError(t, json.Unmarshal([]byte(line), &obj))
+	caller, ok := obj["caller"].(map[string]any)
+	require.True(t, ok)
+	delete(caller, "line")
+	res, err := json.Marshal(obj)
+	require.NoError(t, err)
+	return string(res)
+}
+
+type recordingSink struct {
+	callDepth     int
+	keysAndValues []any
+}
+
+func (s *recordingSink) Init(info logr.RuntimeInfo) {
+	s.callDepth = info.CallDepth
+}
+
+func (s *recordingSink) Enabled(int) bool {
+	return true
+}
+
+func (s *recordingSink) Info(int, string, ...any) {}
+
+func (s *recordingSink) Error(_ error, _ string, keysAndValues ...any) {
+	s.keysAndValues = keysAndValues
+}
+
+func (s *recordingSink) WithValues(...any) logr.LogSink {
+	return s
+}
+
+func (s *recordingSink) WithName(string) logr.LogSink {
+	return s
+}
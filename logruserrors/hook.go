@@ -0,0 +1,93 @@
+// Package logruserrors provides logrus hook that adds fields of errors to log entries.
+package logruserrors
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/maratori/errors"
+)
+
+// DefaultRenamePrefix is used by Rename policy if Hook.RenamePrefix is empty.
+const DefaultRenamePrefix = "error."
+
+// pathSuffix is appended to logrus.ErrorKey to get the key of path added by Hook with Path enabled.
+const pathSuffix = ".path"
+
+// CollisionPolicy defines what Hook does with error field if entry already has a field with the same key.
+type CollisionPolicy int
+
+const (
+	// Rename adds error field with Hook.RenamePrefix prepended to the key.
+	// If entry has a field with the renamed key as well, error field is dropped.
+	Rename CollisionPolicy = iota
+	// KeepEntry drops error field.
+	KeepEntry
+	// Overwrite replaces entry field with error field.
+	Overwrite
+)
+
+// Hook merges fields from [errors.FieldsFromError] into data of log entry.
+// Error is taken from entry.Data[logrus.ErrorKey], see [logrus.WithError].
+type Hook struct {
+	// Policy is applied to error fields that collide with entry fields.
+	Policy CollisionPolicy
+	// RenamePrefix is used by Rename policy. DefaultRenamePrefix is used if it's empty.
+	RenamePrefix string
+	// Path replaces the error in entry with the cause of its first leaf, so the message is constant,
+	// and adds prefixes of the leaf as an ordered list under logrus.ErrorKey+".path" key (e.g. "error.path").
+	// The path is subject to Policy the same as fields.
+	Path bool
+	// GroupSeparator makes the hook flatten groups of fields (see [errors.Group])
+	// into keys joined with the separator, e.g. "db.table" for ".".
+	// By default, a group is added as [errors.FieldList] which is encoded as nested object by JSON formatter.
+	GroupSeparator string
+}
+
+var _ logrus.Hook = (*Hook)(nil)
+
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	err, ok := entry.Data[logrus.ErrorKey].(error)
+	if !ok || err == nil {
+		return nil
+	}
+
+	leaf := errors.Leaves(err)[0]
+	if h.Path {
+		entry.Data[logrus.ErrorKey] = leaf.Cause
+		if len(leaf.Path) > 0 {
+			h.add(entry, logrus.ErrorKey+pathSuffix, leaf.Path)
+		}
+	}
+	fields := errors.NormalizeFields(leaf.Fields)
+	if h.GroupSeparator != "" {
+		fields = fields.Flatten(h.GroupSeparator)
+	}
+	for _, f := range fields { // renamed keys may collide, order must be stable
+		h.add(entry, f.Key, f.Value)
+	}
+	return nil
+}
+
+func (h *Hook) add(entry *logrus.Entry, key string, value any) {
+	if _, exists := entry.Data[key]; !exists || h.Policy == Overwrite {
+		entry.Data[key] = value
+		return
+	}
+	if h.Policy == Rename {
+		renamed := h.renamePrefix() + key
+		if _, exists := entry.Data[renamed]; !exists {
+			entry.Data[renamed] = value
+		}
+	}
+}
+
+func (h *Hook) renamePrefix() string {
+	if h.RenamePrefix == "" {
+		return DefaultRenamePrefix
+	}
+	return h.RenamePrefix
+}
@@ -0,0 +1,229 @@
+package logruserrors_test
+
+import (
+	"bytes"
+	stderrors "errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maratori/errors"
+	"github.com/maratori/errors/logruserrors"
+)
+
+func TestHook(t *testing.T) {
+	t.Parallel()
+	err := errors.New("new err").WithField("key2", 2).Wrap("prefix").WithField("key1", "value1").E()
+
+	t.Run("no error", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(&logruserrors.Hook{
+			Policy:         logruserrors.Rename,
+			RenamePrefix:   "",
+			Path:           false,
+			GroupSeparator: "",
+		})
+		logger.WithField("key1", "entry").Error("failed")
+		require.JSONEq(t, `{"level":"error","msg":"failed","key1":"entry"}`, buf.String())
+	})
+
+	t.Run("foreign error", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(&logruserrors.Hook{
+			Policy:         logruserrors.Rename,
+			RenamePrefix:   "",
+			Path:           false,
+			GroupSeparator: "",
+		})
+		logger.WithError(stderrors.New("new err")).Error("failed")
+		require.JSONEq(t, `{"level":"error","msg":"failed","error":"new err"}`, buf.String())
+	})
+
+	t.Run("fields are merged", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(&logruserrors.Hook{
+			Policy:         logruserrors.Rename,
+			RenamePrefix:   "",
+			Path:           false,
+			GroupSeparator: "",
+		})
+		logger.WithError(err).WithField("key3", "entry").Error("failed")
+		require.JSONEq(t,
+			`{"level":"error","msg":"failed","error":"prefix: new err","key1":"value1","key2":2,"key3":"entry"}`,
+			buf.String(),
+		)
+	})
+
+	t.Run("rename with default prefix", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(&logruserrors.Hook{
+			Policy:         logruserrors.Rename,
+			RenamePrefix:   "",
+			Path:           false,
+			GroupSeparator: "",
+		})
+		logger.WithError(err).WithField("key1", "entry").Error("failed")
+		require.JSONEq(t,
+			`{"level":"error","msg":"failed","error":"prefix: new err","key1":"entry","error.key1":"value1","key2":2}`,
+			buf.String(),
+		)
+	})
+
+	t.Run("rename with custom prefix", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(&logruserrors.Hook{
+			Policy:         logruserrors.Rename,
+			RenamePrefix:   "err_",
+			Path:           false,
+			GroupSeparator: "",
+		})
+		logger.WithError(err).WithField("key1", "entry").Error("failed")
+		require.JSONEq(t,
+			`{"level":"error","msg":"failed","error":"prefix: new err","key1":"entry","err_key1":"value1","key2":2}`,
+			buf.String(),
+		)
+	})
+
+	t.Run("renamed key collides too", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(&logruserrors.Hook{
+			Policy:         logruserrors.Rename,
+			RenamePrefix:   "",
+			Path:           false,
+			GroupSeparator: "",
+		})
+		logger.WithError(err).WithFields(logrus.Fields{"key1": "entry", "error.key1": "entry"}).Error("failed")
+		require.JSONEq(t,
+			`{"level":"error","msg":"failed","error":"prefix: new err","key1":"entry","error.key1":"entry","key2":2}`,
+			buf.String(),
+		)
+	})
+
+	t.Run("keep entry", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(&logruserrors.Hook{
+			Policy:         logruserrors.KeepEntry,
+			RenamePrefix:   "",
+			Path:           false,
+			GroupSeparator: "",
+		})
+		logger.WithError(err).WithField("key1", "entry").Error("failed")
+		require.JSONEq(t,
+			`{"level":"error","msg":"failed","error":"prefix: new err","key1":"entry","key2":2}`,
+			buf.String(),
+		)
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(&logruserrors.Hook{
+			Policy:         logruserrors.Overwrite,
+			RenamePrefix:   "",
+			Path:           false,
+			GroupSeparator: "",
+		})
+		logger.WithError(err).WithField("key1", "entry").Error("failed")
+		require.JSONEq(t,
+			`{"level":"error","msg":"failed","error":"prefix: new err","key1":"value1","key2":2}`,
+			buf.String(),
+		)
+	})
+
+	t.Run("path", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(&logruserrors.Hook{
+			Policy:         logruserrors.Rename,
+			RenamePrefix:   "",
+			Path:           true,
+			GroupSeparator: "",
+		})
+		logger.WithError(errors.Wrap("prefix1", err).E()).Error("failed")
+		require.JSONEq(t, `{
+			"level":"error",
+			"msg":"failed",
+			"error":"new err",
+			"error.path":["prefix1","prefix"],
+			"key1":"value1",
+			"key2":2
+		}`, buf.String())
+	})
+
+	t.Run("path of foreign error", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(&logruserrors.Hook{
+			Policy:         logruserrors.Rename,
+			RenamePrefix:   "",
+			Path:           true,
+			GroupSeparator: "",
+		})
+		logger.WithError(stderrors.New("new err")).Error("failed")
+		require.JSONEq(t, `{"level":"error","msg":"failed","error":"new err"}`, buf.String())
+	})
+
+	t.Run("groups", func(t *testing.T) {
+		t.Parallel()
+		errWithGroup := errors.New("new err").WithGroup("db", errors.Field{Key: "table", Value: "orders"}).E()
+		logger, buf := newLogger(&logruserrors.Hook{
+			Policy:         logruserrors.Rename,
+			RenamePrefix:   "",
+			Path:           false,
+			GroupSeparator: "",
+		})
+		logger.WithError(errWithGroup).Error("failed")
+		require.JSONEq(t, `{"level":"error","msg":"failed","error":"new err","db":{"table":"orders"}}`, buf.String())
+
+		logger, buf = newLogger(&logruserrors.Hook{
+			Policy:         logruserrors.Rename,
+			RenamePrefix:   "",
+			Path:           false,
+			GroupSeparator: ".",
+		})
+		logger.WithError(errWithGroup).Error("failed")
+		require.JSONEq(t, `{"level":"error","msg":"failed","error":"new err","db.table":"orders"}`, buf.String())
+	})
+
+	t.Run("normalized values", func(t *testing.T) {
+		t.Parallel()
+		errWithValues := errors.New("new err").
+			With("cause", errors.New("inner").WithField("id", 1).E(), "raw", []byte("bytes"), "ids", []uint{1, 2}).
+			E()
+		logger, buf := newLogger(&logruserrors.Hook{
+			Policy:         logruserrors.Rename,
+			RenamePrefix:   "",
+			Path:           false,
+			GroupSeparator: "",
+		})
+		logger.WithError(errWithValues).Error("failed")
+		require.JSONEq(t, `{
+			"level":"error","msg":"failed","error":"new err",
+			"cause":{"message":"inner","id":1},"raw":"bytes","ids":[1,2]
+		}`, buf.String())
+	})
+
+	t.Run("entry is reusable", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(&logruserrors.Hook{
+			Policy:         logruserrors.Rename,
+			RenamePrefix:   "",
+			Path:           false,
+			GroupSeparator: "",
+		})
+		entry := logger.WithField("key1", "entry")
+		entry.WithError(err).Error("failed")
+		buf.Reset()
+		entry.Error("failed")
+		require.JSONEq(t, `{"level":"error","msg":"failed","key1":"entry"}`, buf.String())
+	})
+}
+
+func newLogger(hook logrus.Hook) (*logrus.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&logrus.JSONFormatter{ //nolint:exhaustruct // only timestamp is disabled
+		DisableTimestamp: true,
+	})
+	logger.AddHook(hook)
+	return logger, &buf
+}
@@ -0,0 +1,182 @@
+package errors
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	messageKey = "message"
+	leavesKey  = "leaves"
+	// maxNormalizeDepth limits nesting of normalized values, deeper values are replaced with their type name.
+	// It prevents infinite recursion on cyclic values (formatting them with "%+v" doesn't stop either).
+	maxNormalizeDepth = 16
+)
+
+// Normalize converts v into one of a small closed set of types, so logger adapters and encoders
+// render it the same way regardless of the original type:
+//   - string;
+//   - int64 for all signed integers and unsigned integers up to [math.MaxInt64], larger ones become string;
+//   - float64 for all floats;
+//   - bool;
+//   - [time.Time];
+//   - [time.Duration];
+//   - FieldList for a group, see Group;
+//   - []any for a list, each element is normalized as well;
+//   - nil for nil, nil pointer, nil slice and nil map.
+//
+// Values of other types are converted as follows:
+//   - error built with this package (or wrapping such error) becomes a group with "message" and fields,
+//     several leaves are added as a list under "leaves" key, other errors become their message;
+//     fields named "message" or "leaves" are renamed with FieldList.RenameReserved;
+//   - [json.RawMessage] becomes string, []byte becomes string if it's valid UTF-8 or base64 otherwise;
+//   - [fmt.Stringer] becomes the result of String;
+//   - slice and array become list, map becomes group with keys formatted with "%v" and sorted;
+//   - pointer is replaced with the value it points to;
+//   - struct with exported fields becomes group the same way as in ErrorBuilder.WithStruct;
+//   - anything else (e.g. complex number, func, channel) becomes string formatted with "%+v".
+//
+// Lazy values are computed and normalized. Values nested deeper than 16 levels are replaced with their type name.
+func Normalize(v any) any {
+	return normalize(v, 0)
+}
+
+// NormalizeFields returns fields with values converted by Normalize.
+func NormalizeFields(fields FieldList) FieldList {
+	return normalizeFields(fields, 0)
+}
+
+func normalizeFields(fields FieldList, depth int) FieldList {
+	if fields == nil {
+		return nil
+	}
+	res := make(FieldList, 0, len(fields))
+	for _, f := range fields {
+		res = append(res, Field{
+			Key:   f.Key,
+			Value: normalize(f.Value, depth+1),
+		})
+	}
+	return res
+}
+
+//nolint:cyclop,gocyclo // flat list of supported types
+func normalize(v any, depth int) any {
+	if depth > maxNormalizeDepth {
+		return fmt.Sprintf("%T", v)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() { //nolint:exhaustive // other kinds are checked below
+	case reflect.Invalid:
+		return nil
+	case reflect.Pointer, reflect.Slice, reflect.Map:
+		if rv.IsNil() { // methods of nil pointer may panic
+			return nil
+		}
+	}
+	switch x := v.(type) {
+	case string, int64, float64, bool, time.Time, time.Duration:
+		return x
+	case *time.Time: // checked before fmt.Stringer
+		return *x
+	case *time.Duration:
+		return *x
+	case *lazyValue:
+		return normalize(x.resolve(), depth)
+	case FieldList:
+		return normalizeFields(x, depth)
+	case json.RawMessage:
+		return string(x)
+	case []byte:
+		if utf8.Valid(x) {
+			return string(x)
+		}
+		return base64.StdEncoding.EncodeToString(x)
+	case error:
+		if needsUnwrap(x) {
+			return normalizeError(x, depth)
+		}
+		return x.Error()
+	case fmt.Stringer:
+		return x.String()
+	}
+	switch rv.Kind() { //nolint:exhaustive // other kinds are formatted
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u <= math.MaxInt64 {
+			return int64(u)
+		}
+		return strconv.FormatUint(u, 10)
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Slice, reflect.Array:
+		res := make([]any, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			res = append(res, normalize(rv.Index(i).Interface(), depth+1))
+		}
+		return res
+	case reflect.Map:
+		return normalizeMap(rv, depth)
+	case reflect.Pointer:
+		return normalize(rv.Elem().Interface(), depth)
+	case reflect.Struct:
+		if isNestedStruct(rv.Type()) {
+			return normalizeFields(structFields(rv, 0), depth)
+		}
+	}
+	return fmt.Sprintf("%+v", v)
+}
+
+func normalizeMap(rv reflect.Value, depth int) FieldList {
+	res := make(FieldList, 0, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		res = append(res, Field{
+			Key:   fmt.Sprintf("%v", iter.Key().Interface()),
+			Value: normalize(iter.Value().Interface(), depth+1),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Key < res[j].Key
+	})
+	return res
+}
+
+// normalizeError renders a single leaf as a group with message and fields.
+// Several leaves are rendered as a group with message and list of leaves.
+func normalizeError(err error, depth int) FieldList {
+	leaves := Leaves(err)
+	if len(leaves) == 1 {
+		return normalizeLeaf(err.Error(), leaves[0].Fields, depth)
+	}
+	list := make([]any, 0, len(leaves))
+	for _, leaf := range leaves {
+		list = append(list, normalizeLeaf(leaf.Error(), leaf.Fields, depth+1))
+	}
+	return FieldList{
+		{Key: messageKey, Value: err.Error()},
+		{Key: leavesKey, Value: list},
+	}
+}
+
+func normalizeLeaf(msg string, fields FieldList, depth int) FieldList {
+	res := make(FieldList, 0, len(fields)+1)
+	res = append(res, Field{
+		Key:   messageKey,
+		Value: msg,
+	})
+	return append(res, normalizeFields(fields, depth).RenameReserved(messageKey, leavesKey)...)
+}
@@ -0,0 +1,172 @@
+package errors_test
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maratori/errors"
+)
+
+type normalizeStatus int
+
+func (s normalizeStatus) String() string {
+	return fmt.Sprintf("status-%d", int(s))
+}
+
+type normalizeString string
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	var nilPointer *int
+	var nilError error
+	one := 1
+	tests := []struct {
+		name     string
+		value    any
+		expected any
+	}{
+		{name: "nil", value: nil, expected: nil},
+		{name: "nil pointer", value: nilPointer, expected: nil},
+		{name: "nil error", value: nilError, expected: nil},
+		{name: "nil slice", value: []int(nil), expected: nil},
+		{name: "nil map", value: map[string]int(nil), expected: nil},
+		{name: "string", value: "value", expected: "value"},
+		{name: "named string", value: normalizeString("value"), expected: "value"},
+		{name: "int", value: 1, expected: int64(1)},
+		{name: "int8", value: int8(-1), expected: int64(-1)},
+		{name: "uint", value: uint(1), expected: int64(1)},
+		{name: "large uint", value: uint64(math.MaxUint64), expected: "18446744073709551615"},
+		{name: "float", value: float32(0.5), expected: float64(0.5)},
+		{name: "bool", value: true, expected: true},
+		{name: "time", value: now, expected: now},
+		{name: "time pointer", value: &now, expected: now},
+		{name: "duration", value: time.Second, expected: time.Second},
+		{name: "pointer", value: &one, expected: int64(1)},
+		{name: "bytes", value: []byte("bytes"), expected: "bytes"},
+		{name: "binary bytes", value: []byte{0xff, 0xfe}, expected: "//4="},
+		{name: "raw json", value: json.RawMessage(`{"key":1}`), expected: `{"key":1}`},
+		{name: "stringer", value: normalizeStatus(1), expected: "status-1"},
+		{name: "foreign error", value: stderrors.New("foreign"), expected: "foreign"},
+		{name: "ip", value: net.IPv4(127, 0, 0, 1), expected: "127.0.0.1"},
+		{name: "complex", value: complex(1, 2), expected: "(1+2i)"},
+		{name: "slice", value: []any{1, "value", []int{2}}, expected: []any{int64(1), "value", []any{int64(2)}}},
+		{name: "array", value: [2]uint8{1, 2}, expected: []any{int64(1), int64(2)}},
+		{
+			name:  "map",
+			value: map[int]any{2: "two", 1: []string{"one"}},
+			expected: errors.FieldList{
+				{Key: "1", Value: []any{"one"}},
+				{Key: "2", Value: "two"},
+			},
+		},
+		{
+			name: "struct",
+			value: Customer{
+				ID:   "c1",
+				Name: "secret",
+			},
+			expected: errors.FieldList{{Key: "id", Value: "c1"}},
+		},
+		{
+			name:     "struct without exported fields",
+			value:    unexportedStruct{name: "name"},
+			expected: "{name:name}",
+		},
+		{
+			name: "group",
+			value: errors.FieldList{
+				{Key: "key", Value: 1},
+				{Key: "group", Value: errors.FieldList{{Key: "k", Value: 2}}},
+			},
+			expected: errors.FieldList{
+				{Key: "key", Value: int64(1)},
+				{Key: "group", Value: errors.FieldList{{Key: "k", Value: int64(2)}}},
+			},
+		},
+		{
+			name:     "lazy",
+			value:    errors.Lazy(func() any { return uint8(1) }),
+			expected: int64(1),
+		},
+		{
+			name: "error",
+			value: errors.New("new err").WithField("key", 1).Wrap("prefix").
+				WithField("nested", errors.New("nested").E()).
+				E(),
+			expected: errors.FieldList{
+				{Key: "message", Value: "prefix: new err"},
+				{Key: "key", Value: int64(1)},
+				{Key: "nested", Value: errors.FieldList{{Key: "message", Value: "nested"}}},
+			},
+		},
+		{
+			name: "joined errors",
+			value: errors.Join(
+				errors.New("err1").WithField("key", 1).E(),
+				errors.New("err2").E(),
+			),
+			expected: errors.FieldList{
+				{Key: "message", Value: "err1\nerr2"},
+				{Key: "leaves", Value: []any{
+					errors.FieldList{{Key: "message", Value: "err1"}, {Key: "key", Value: int64(1)}},
+					errors.FieldList{{Key: "message", Value: "err2"}},
+				}},
+			},
+		},
+		{
+			name:  "foreign wrapper",
+			value: fmt.Errorf("foreign: %w", errors.New("new err").WithField("key", 1).E()),
+			expected: errors.FieldList{
+				{Key: "message", Value: "foreign: new err"},
+				{Key: "key", Value: int64(1)},
+			},
+		},
+		{
+			name:  "error with reserved keys",
+			value: errors.New("new err").With("message", "field", "leaves", 1).E(),
+			expected: errors.FieldList{
+				{Key: "message", Value: "new err"},
+				{Key: "message#1", Value: "field"},
+				{Key: "leaves#1", Value: int64(1)},
+			},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, errors.Normalize(tc.value))
+		})
+	}
+
+	t.Run("chan", func(t *testing.T) {
+		t.Parallel()
+		require.Regexp(t, `^0x[0-9a-f]+$`, errors.Normalize(make(chan int)))
+	})
+
+	t.Run("cyclic value", func(t *testing.T) {
+		t.Parallel()
+		cycle := []any{nil}
+		cycle[0] = cycle
+		require.NotPanics(t, func() {
+			errors.Normalize(cycle)
+		})
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		t.Parallel()
+		require.Nil(t, errors.NormalizeFields(nil))
+		require.Equal(t, errors.FieldList{
+			{Key: "key", Value: int64(1)},
+		}, errors.NormalizeFields(errors.FieldList{{Key: "key", Value: 1}}))
+	})
+}
@@ -0,0 +1,73 @@
+package errors
+
+// FieldOrigin is a value of a field set by one wrapper along the path of a leaf.
+type FieldOrigin struct {
+	Value any
+	// Level is the position of the wrapper among wrappers with fields, 0 for the innermost one.
+	Level int
+	// Path contains prefixes added inside the wrapper from the outermost to the innermost, see Leaf.Path.
+	Path []string
+	// Caller is the call site of the wrapper. It's captured only if CaptureStacks is enabled.
+	Caller Stack
+}
+
+// FieldProvenance contains all values of a field set along the path of a leaf from the innermost to the outermost.
+type FieldProvenance struct {
+	Key    string
+	Values []FieldOrigin
+}
+
+type fieldOrigin struct {
+	fields FieldList
+	path   []string
+	caller Stack
+}
+
+// Provenance returns fields of the first leaf of the errors tree (see FieldsFromError) with all their values,
+// including the ones shadowed by the conflict policy, and wrappers that set them.
+// Keys are ordered by the first insertion. A group is reported as a single field, its values aren't merged.
+// Fields of a leaf extracted from another error (see Leaves) are reported as set by the leaf itself.
+func Provenance(err error) []FieldProvenance {
+	o := options{
+		policy:     InnerWins, // policy doesn't matter, but PanicOnConflict must not panic
+		provenance: true,
+	}
+	errs := wrapper{err: err}.Errors(o)
+	if len(errs) == 0 {
+		return nil
+	}
+	var res []FieldProvenance
+	index := map[string]int{}
+	for level, origin := range errs[0].origins {
+		for _, f := range origin.fields {
+			i, ok := index[f.Key]
+			if !ok {
+				i = len(res)
+				index[f.Key] = i
+				res = append(res, FieldProvenance{
+					Key:    f.Key,
+					Values: nil,
+				})
+			}
+			res[i].Values = append(res[i].Values, FieldOrigin{
+				Value:  f.Value,
+				Level:  level,
+				Path:   origin.path,
+				Caller: origin.caller,
+			})
+		}
+	}
+	return res
+}
+
+// withOrigin makes fields of a leaf built elsewhere the first origin if provenance is collected.
+func (o options) withOrigin(leaf errorWithFields) errorWithFields {
+	if o.provenance && len(leaf.origins) == 0 && len(leaf.fields) > 0 {
+		leaf.origins = []fieldOrigin{{
+			fields: leaf.fields,
+			path:   nil,
+			caller: nil,
+		}}
+	}
+	return leaf
+}
@@ -0,0 +1,138 @@
+package errors_test
+
+import (
+	stderrors "errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maratori/errors"
+)
+
+func TestProvenance(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil error", func(t *testing.T) {
+		t.Parallel()
+		require.Nil(t, errors.Provenance(nil))
+	})
+
+	t.Run("foreign error", func(t *testing.T) {
+		t.Parallel()
+		require.Nil(t, errors.Provenance(stderrors.New("new err")))
+	})
+
+	t.Run("shadowed values", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").
+			WithField("key1", 1).
+			Wrap("prefix1").
+			WithFields(errors.Fields{"key2": 2, "key1": "shadowed"}).
+			Wrap("prefix2").
+			WithGroup("db", errors.Field{Key: "table", Value: "orders"}).
+			WithField("key2", "shadowed").
+			E()
+		require.Equal(t, []errors.FieldProvenance{
+			{Key: "key1", Values: []errors.FieldOrigin{
+				{Value: 1, Level: 0, Path: nil, Caller: nil},
+				{Value: "shadowed", Level: 1, Path: []string{"prefix1"}, Caller: nil},
+			}},
+			{Key: "key2", Values: []errors.FieldOrigin{
+				{Value: 2, Level: 1, Path: []string{"prefix1"}, Caller: nil},
+				{Value: "shadowed", Level: 3, Path: []string{"prefix2", "prefix1"}, Caller: nil},
+			}},
+			{Key: "db", Values: []errors.FieldOrigin{
+				{
+					Value:  errors.FieldList{{Key: "table", Value: "orders"}},
+					Level:  2,
+					Path:   []string{"prefix2", "prefix1"},
+					Caller: nil,
+				},
+			}},
+		}, errors.Provenance(err))
+	})
+
+	t.Run("first leaf", func(t *testing.T) {
+		t.Parallel()
+		err := errors.Join(
+			errors.New("err1").WithField("key", 1).E(),
+			errors.New("err2").WithField("key", 2).E(),
+		)
+		err = errors.Wrap("prefix", err).WithField("key", 3).E()
+		require.Equal(t, []errors.FieldProvenance{
+			{Key: "key", Values: []errors.FieldOrigin{
+				{Value: 1, Level: 0, Path: nil, Caller: nil},
+				{Value: 3, Level: 1, Path: []string{"prefix"}, Caller: nil},
+			}},
+		}, errors.Provenance(err))
+	})
+
+	t.Run("wrappers without fields aren't levels", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithField("key", 1).WithFields(nil).With().WithField("key", 2).E()
+		require.Equal(t, []errors.FieldProvenance{
+			{Key: "key", Values: []errors.FieldOrigin{
+				{Value: 1, Level: 0, Path: nil, Caller: nil},
+				{Value: 2, Level: 1, Path: nil, Caller: nil},
+			}},
+		}, errors.Provenance(err))
+	})
+
+	t.Run("foreign wrapper", func(t *testing.T) {
+		t.Parallel()
+		err := fmt.Errorf("foreign: %w", errors.New("new err").WithField("key", 1).E())
+		err = errors.WithField(err, "key", 2).E()
+		require.Equal(t, []errors.FieldProvenance{
+			{Key: "key", Values: []errors.FieldOrigin{
+				{Value: 1, Level: 0, Path: nil, Caller: nil},
+				{Value: 2, Level: 1, Path: []string{"foreign"}, Caller: nil},
+			}},
+		}, errors.Provenance(err))
+	})
+
+	t.Run("leaf", func(t *testing.T) {
+		t.Parallel()
+		leaf := errors.Leaves(errors.Wrap("prefix", errors.New("new err").WithField("key", 1).E()).E())[0]
+		err := errors.WithField(leaf, "key", 2).E()
+		require.Equal(t, []errors.FieldProvenance{
+			{Key: "key", Values: []errors.FieldOrigin{
+				{Value: 1, Level: 0, Path: nil, Caller: nil},
+				{Value: 2, Level: 1, Path: []string{"prefix"}, Caller: nil},
+			}},
+		}, errors.Provenance(err))
+	})
+
+	t.Run("panic policy is ignored", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithField("key", 1).WithField("key", 2).E()
+		require.Len(t, errors.Provenance(err)[0].Values, 2)
+		require.Panics(t, func() {
+			errors.FieldsFromError(err, errors.WithConflictPolicy(errors.PanicOnConflict))
+		})
+	})
+}
+
+//nolint:paralleltest // modifies global switch
+func TestProvenanceCaller(t *testing.T) {
+	errors.CaptureStacks(true)
+	t.Cleanup(func() {
+		errors.CaptureStacks(false)
+	})
+
+	err1, line1 := errors.New("new err").WithField("key", 1).E(), currentLine()
+	err2, line2 := errors.WithField(err1, "key", 2).E(), currentLine()
+	err3, line3 := errors.WithFields(err2, errors.Fields{"key": 3}).E(), currentLine()
+	err4, line4 := errors.Err(err3).With(errors.Field{Key: "key", Value: 4}).E(), currentLine()
+	err5, line5 := errors.Err(err4).WithGroup("key", errors.Field{Key: "k", Value: 5}).E(), currentLine()
+	err6, line6 := errors.Err(err5).WithFields(errors.Fields{"key": 6}).E(), currentLine()
+
+	values := errors.Provenance(err6)[0].Values
+	require.Len(t, values, 6)
+	for i, line := range []int{line1, line2, line3, line4, line5, line6} {
+		frames := values[i].Caller.Frames()
+		require.Len(t, frames, 1)
+		require.Equal(t, line, frames[0].Line)
+		require.Contains(t, frames[0].File, "provenance_test.go")
+	}
+}
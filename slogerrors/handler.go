@@ -0,0 +1,242 @@
+//go:build go1.21
+
+// Package slogerrors provides [slog.Handler] middleware that expands errors into structured attributes.
+package slogerrors
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/maratori/errors"
+)
+
+const (
+	messageKey = "message"
+	pathKey    = "path"
+	leavesKey  = "leaves"
+)
+
+type HandlerOptions struct {
+	// Flatten puts error message under the error key and fields next to it.
+	// By default, message and fields are nested into a group under the error key.
+	Flatten bool
+	// FieldPrefix is prepended to keys of flattened fields, e.g. "error.".
+	// It is ignored if Flatten is false.
+	FieldPrefix string
+	// SplitLeaves makes the handler emit one record per leaf of joined error.
+	SplitLeaves bool
+	// Path replaces error message with the constant message of the leaf without prefixes
+	// and adds prefixes as an ordered list under "path" key (FieldPrefix+"path" if Flatten is true).
+	// The first leaf is used for joined error, the same as for fields.
+	Path bool
+	// GroupSeparator makes the handler flatten groups of fields (see [errors.Group])
+	// into keys joined with the separator, e.g. "db.table" for ".".
+	// By default, groups are rendered as [slog.Group].
+	GroupSeparator string
+}
+
+// NewHandler returns [slog.Handler] that expands error attributes and passes records to next.
+// Only errors built with this package are expanded (see [errors.IsStructured]), other errors are kept as is.
+// Error is expanded into its message, fields from [errors.FieldsFromError] and leaves from [errors.Errors].
+// Fields colliding with keys added next to them ("message", "path" and "leaves", or the error key
+// if Flatten is true) are renamed with [errors.FieldList.RenameReserved].
+func NewHandler(next slog.Handler, opts *HandlerOptions) slog.Handler {
+	h := &handler{
+		next: next,
+		opts: HandlerOptions{
+			Flatten:        false,
+			FieldPrefix:    "",
+			SplitLeaves:    false,
+			Path:           false,
+			GroupSeparator: "",
+		},
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+type handler struct {
+	next slog.Handler
+	opts HandlerOptions
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	split := -1
+	var leaves []error
+	record.Attrs(func(a slog.Attr) bool {
+		if err, ok := errorValue(a.Value); ok && h.opts.SplitLeaves && split < 0 {
+			if errs := errors.Errors(err); len(errs) > 1 {
+				split = len(attrs)
+				leaves = errs
+			}
+		}
+		attrs = append(attrs, a)
+		return true
+	})
+
+	if split < 0 {
+		return h.next.Handle(ctx, h.newRecord(record, attrs))
+	}
+
+	for _, leaf := range leaves {
+		attrs[split].Value = slog.AnyValue(leaf)
+		err := h.next.Handle(ctx, h.newRecord(record, attrs))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{
+		next: h.next.WithAttrs(h.expand(attrs)),
+		opts: h.opts,
+	}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{
+		next: h.next.WithGroup(name),
+		opts: h.opts,
+	}
+}
+
+func (h *handler) newRecord(record slog.Record, attrs []slog.Attr) slog.Record {
+	res := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	res.AddAttrs(h.expand(attrs)...)
+	return res
+}
+
+func (h *handler) expand(attrs []slog.Attr) []slog.Attr {
+	res := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		err, ok := errorValue(a.Value)
+		switch {
+		case a.Value.Kind() == slog.KindGroup:
+			res = append(res, slog.Attr{
+				Key:   a.Key,
+				Value: slog.GroupValue(h.expand(a.Value.Group())...),
+			})
+		case !ok:
+			res = append(res, a)
+		case h.opts.Flatten:
+			res = h.appendFlatten(res, a.Key, err)
+		default:
+			res = append(res, slog.Attr{
+				Key:   a.Key,
+				Value: h.nested(err),
+			})
+		}
+	}
+	return res
+}
+
+func (h *handler) appendFlatten(attrs []slog.Attr, key string, err error) []slog.Attr {
+	leaves := errors.Leaves(err)
+	reserved := []string{pathKey, leavesKey}
+	if strings.HasPrefix(key, h.opts.FieldPrefix) { // error message is added under the key
+		reserved = append(reserved, strings.TrimPrefix(key, h.opts.FieldPrefix))
+	}
+	attrs = h.appendMessage(attrs, key, h.opts.FieldPrefix+pathKey, err, leaves[0])
+	attrs = h.appendFields(attrs, h.opts.FieldPrefix, leaves[0].Fields, reserved...)
+	if len(leaves) > 1 {
+		attrs = append(attrs, slog.Attr{
+			Key:   h.opts.FieldPrefix + leavesKey,
+			Value: h.leavesValue(leaves),
+		})
+	}
+	return attrs
+}
+
+func (h *handler) nested(err error) slog.Value {
+	leaves := errors.Leaves(err)
+	if len(leaves) == 1 {
+		return h.leafValue(leaves[0])
+	}
+	attrs := make([]slog.Attr, 0, 3) //nolint:mnd // message, path and leaves
+	attrs = h.appendMessage(attrs, messageKey, pathKey, err, leaves[0])
+	attrs = append(attrs, slog.Attr{
+		Key:   leavesKey,
+		Value: h.leavesValue(leaves),
+	})
+	return slog.GroupValue(attrs...)
+}
+
+func (h *handler) leavesValue(leaves []errors.Leaf) slog.Value {
+	attrs := make([]slog.Attr, 0, len(leaves))
+	for i, leaf := range leaves {
+		attrs = append(attrs, slog.Attr{
+			Key:   strconv.Itoa(i),
+			Value: h.leafValue(leaf),
+		})
+	}
+	return slog.GroupValue(attrs...)
+}
+
+func (h *handler) leafValue(leaf errors.Leaf) slog.Value {
+	attrs := make([]slog.Attr, 0, len(leaf.Fields)+2) //nolint:mnd // message and path
+	attrs = h.appendMessage(attrs, messageKey, pathKey, leaf, leaf)
+	return slog.GroupValue(h.appendFields(attrs, "", leaf.Fields, messageKey, pathKey, leavesKey)...)
+}
+
+// appendMessage appends message of err or, if Path option is set, message and path of leaf.
+func (h *handler) appendMessage(attrs []slog.Attr, key, pathKey string, err error, leaf errors.Leaf) []slog.Attr {
+	if !h.opts.Path {
+		return append(attrs, slog.String(key, err.Error()))
+	}
+	attrs = append(attrs, slog.String(key, leaf.Message))
+	if len(leaf.Path) > 0 {
+		attrs = append(attrs, slog.Any(pathKey, leaf.Path))
+	}
+	return attrs
+}
+
+// appendFields renames fields with reserved keys (without prefix), see [errors.FieldList.RenameReserved].
+func (h *handler) appendFields(
+	attrs []slog.Attr,
+	prefix string,
+	fields errors.FieldList,
+	reserved ...string,
+) []slog.Attr {
+	fields = errors.NormalizeFields(fields)
+	if h.opts.GroupSeparator != "" {
+		fields = fields.Flatten(h.opts.GroupSeparator)
+	}
+	return appendFields(attrs, prefix, fields.RenameReserved(reserved...))
+}
+
+func appendFields(attrs []slog.Attr, prefix string, fields errors.FieldList) []slog.Attr {
+	for _, f := range fields {
+		if group, ok := f.Value.(errors.FieldList); ok {
+			attrs = append(attrs, slog.Attr{
+				Key:   prefix + f.Key,
+				Value: slog.GroupValue(appendFields(make([]slog.Attr, 0, len(group)), "", group)...),
+			})
+			continue
+		}
+		attrs = append(attrs, slog.Any(prefix+f.Key, f.Value))
+	}
+	return attrs
+}
+
+// errorValue returns the error held by v if it is built with this package, see [errors.IsStructured].
+// Other errors are kept as is.
+func errorValue(v slog.Value) (error, bool) {
+	switch v.Kind() { //nolint:exhaustive // only these kinds may hold an error
+	case slog.KindAny, slog.KindLogValuer:
+		err, ok := v.Any().(error)
+		return err, ok && errors.IsStructured(err)
+	default:
+		return nil, false
+	}
+}
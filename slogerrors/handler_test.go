@@ -0,0 +1,288 @@
+//go:build go1.21
+
+package slogerrors_test
+
+import (
+	"bytes"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maratori/errors"
+	"github.com/maratori/errors/slogerrors"
+)
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+	single := errors.New("new err").WithField("key2", 2).Wrap("prefix").WithField("key1", "value1").E()
+	joined := errors.Wrap("prefix", errors.Join(
+		errors.New("new err 1").WithField("key1", "value1").E(),
+		errors.New("new err 2").WithField("key2", "value2").E(),
+	)).WithField("key3", "value3").E()
+
+	t.Run("nested", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(nil)
+		logger.Error("failed", "err", single)
+		require.JSONEq(t,
+			`{"msg":"failed","err":{"message":"prefix: new err","key1":"value1","key2":2}}`,
+			buf.String(),
+		)
+	})
+
+	t.Run("nested joined", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(nil)
+		logger.Error("failed", "err", joined)
+		require.JSONEq(t, `{"msg":"failed","err":{
+			"message":"prefix: new err 1\nnew err 2",
+			"leaves":{
+				"0":{"message":"prefix: new err 1","key1":"value1","key3":"value3"},
+				"1":{"message":"prefix: new err 2","key2":"value2","key3":"value3"}
+			}
+		}}`, buf.String())
+	})
+
+	t.Run("order of fields", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(nil)
+		logger.Error("failed", "err", single)
+		require.Equal(t,
+			`{"msg":"failed","err":{"message":"prefix: new err","key2":2,"key1":"value1"}}`+"\n",
+			buf.String(),
+		)
+	})
+
+	t.Run("nested foreign error", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(nil)
+		logger.Error("failed", "err", stderrors.New("new err"))
+		require.JSONEq(t, `{"msg":"failed","err":"new err"}`, buf.String())
+	})
+
+	t.Run("nested foreign wrapper", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(nil)
+		std := stderrors.New("new err")
+		logger.Error("failed", "err", fmt.Errorf("ctx: %w", single), "std", fmt.Errorf("ctx: %w", std))
+		require.JSONEq(t, `{
+			"msg":"failed",
+			"err":{"message":"ctx: prefix: new err","key1":"value1","key2":2},
+			"std":"ctx: new err"
+		}`, buf.String())
+	})
+
+	t.Run("flatten with prefix", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(&slogerrors.HandlerOptions{
+			Flatten:        true,
+			FieldPrefix:    "error.",
+			SplitLeaves:    false,
+			Path:           false,
+			GroupSeparator: "",
+		})
+		logger.Error("failed", "err", single)
+		require.JSONEq(t,
+			`{"msg":"failed","err":"prefix: new err","error.key1":"value1","error.key2":2}`,
+			buf.String(),
+		)
+	})
+
+	t.Run("flatten joined", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(&slogerrors.HandlerOptions{
+			Flatten:        true,
+			FieldPrefix:    "",
+			SplitLeaves:    false,
+			Path:           false,
+			GroupSeparator: "",
+		})
+		logger.Error("failed", "err", joined)
+		require.JSONEq(t, `{
+			"msg":"failed",
+			"err":"prefix: new err 1\nnew err 2",
+			"key1":"value1",
+			"key3":"value3",
+			"leaves":{
+				"0":{"message":"prefix: new err 1","key1":"value1","key3":"value3"},
+				"1":{"message":"prefix: new err 2","key2":"value2","key3":"value3"}
+			}
+		}`, buf.String())
+	})
+
+	t.Run("split leaves", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(&slogerrors.HandlerOptions{
+			Flatten:        true,
+			FieldPrefix:    "",
+			SplitLeaves:    true,
+			Path:           false,
+			GroupSeparator: "",
+		})
+		logger.Error("failed", "err", joined, "other", 1)
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		require.JSONEq(t,
+			`{"msg":"failed","err":"prefix: new err 1","key1":"value1","key3":"value3","other":1}`,
+			lines[0],
+		)
+		require.JSONEq(t,
+			`{"msg":"failed","err":"prefix: new err 2","key2":"value2","key3":"value3","other":1}`,
+			lines[1],
+		)
+	})
+
+	t.Run("nested path", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(&slogerrors.HandlerOptions{
+			Flatten:        false,
+			FieldPrefix:    "",
+			SplitLeaves:    false,
+			Path:           true,
+			GroupSeparator: "",
+		})
+		logger.Error("failed", "err", errors.Wrap("prefix1", single).E(), "foreign", stderrors.New("new err"))
+		require.JSONEq(t, `{
+			"msg":"failed",
+			"err":{"message":"new err","path":["prefix1","prefix"],"key1":"value1","key2":2},
+			"foreign":"new err"
+		}`, buf.String())
+	})
+
+	t.Run("nested joined path", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(&slogerrors.HandlerOptions{
+			Flatten:        false,
+			FieldPrefix:    "",
+			SplitLeaves:    false,
+			Path:           true,
+			GroupSeparator: "",
+		})
+		logger.Error("failed", "err", joined)
+		require.JSONEq(t, `{"msg":"failed","err":{
+			"message":"new err 1",
+			"path":["prefix"],
+			"leaves":{
+				"0":{"message":"new err 1","path":["prefix"],"key1":"value1","key3":"value3"},
+				"1":{"message":"new err 2","path":["prefix"],"key2":"value2","key3":"value3"}
+			}
+		}}`, buf.String())
+	})
+
+	t.Run("flatten path", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(&slogerrors.HandlerOptions{
+			Flatten:        true,
+			FieldPrefix:    "error.",
+			SplitLeaves:    false,
+			Path:           true,
+			GroupSeparator: "",
+		})
+		logger.Error("failed", "error", single)
+		require.JSONEq(t,
+			`{"msg":"failed","error":"new err","error.path":["prefix"],"error.key1":"value1","error.key2":2}`,
+			buf.String(),
+		)
+	})
+
+	t.Run("field groups", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").
+			WithGroup("db", errors.Field{Key: "table", Value: "orders"}, errors.Group("query",
+				errors.Field{Key: "id", Value: 1},
+			)).
+			E()
+		logger, buf := newLogger(nil)
+		logger.Error("failed", "err", err)
+		require.JSONEq(t,
+			`{"msg":"failed","err":{"message":"new err","db":{"table":"orders","query":{"id":1}}}}`,
+			buf.String(),
+		)
+
+		logger, buf = newLogger(&slogerrors.HandlerOptions{
+			Flatten:        true,
+			FieldPrefix:    "error.",
+			SplitLeaves:    false,
+			Path:           false,
+			GroupSeparator: "_",
+		})
+		logger.Error("failed", "err", err)
+		require.JSONEq(t,
+			`{"msg":"failed","err":"new err","error.db_table":"orders","error.db_query_id":1}`,
+			buf.String(),
+		)
+	})
+
+	t.Run("normalized values", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").
+			With("cause", errors.New("inner").WithField("id", 1).E(), "raw", []byte("bytes"), "ids", []uint{1, 2}).
+			E()
+		logger, buf := newLogger(nil)
+		logger.Error("failed", "err", err)
+		require.JSONEq(t,
+			`{"msg":"failed","err":{"message":"new err","cause":{"message":"inner","id":1},"raw":"bytes","ids":[1,2]}}`,
+			buf.String(),
+		)
+	})
+
+	t.Run("with attrs and groups", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(nil)
+		logger.With("err", single).WithGroup("group").Error("failed", slog.Group("inner", "err", single))
+		require.JSONEq(t, `{
+			"msg":"failed",
+			"err":{"message":"prefix: new err","key1":"value1","key2":2},
+			"group":{"inner":{"err":{"message":"prefix: new err","key1":"value1","key2":2}}}
+		}`, buf.String())
+	})
+
+	t.Run("reserved keys", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").With("message", "field", "path", 1, "leaves", 2, "err", 3).E()
+		logger, buf := newLogger(nil)
+		logger.Error("failed", "err", err)
+		flat, flatBuf := newLogger(&slogerrors.HandlerOptions{
+			Flatten:        true,
+			FieldPrefix:    "",
+			SplitLeaves:    false,
+			Path:           false,
+			GroupSeparator: "",
+		})
+		flat.Error("failed", "err", err)
+		require.Equal(t,
+			`{"msg":"failed","err":{"message":"new err","message#1":"field","path#1":1,"leaves#1":2,"err":3}}`+"\n",
+			buf.String(),
+		)
+		require.Equal(t,
+			`{"msg":"failed","err":"new err","message":"field","path#1":1,"leaves#1":2,"err#1":3}`+"\n",
+			flatBuf.String(),
+		)
+	})
+
+	t.Run("non-error attrs are untouched", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger(nil)
+		logger.Info("ok", "key", "value", "err", nil)
+		require.JSONEq(t, `{"msg":"ok","key":"value","err":null}`, buf.String())
+	})
+}
+
+func newLogger(opts *slogerrors.HandlerOptions) (*slog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	next := slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		AddSource: false,
+		Level:     nil,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey) {
+				return slog.Attr{} //nolint:exhaustruct // empty attr is dropped by handler
+			}
+			return a
+		},
+	})
+	return slog.New(slogerrors.NewHandler(next, opts)), &buf
+}
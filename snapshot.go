@@ -0,0 +1,192 @@
+package errors
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+//nolint:gochecknoglobals // global switch is the way to enable snapshots for the whole application
+var snapshotBudget int64
+
+//nolint:gochecknoglobals // types are compared on each copied value
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	lazyType  = reflect.TypeOf(&lazyValue{}) //nolint:exhaustruct // only type is needed
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// SnapshotFields enables deep copy of values of fields added by WithFields, WithField, With and WithGroup,
+// so mutation of a slice, map or struct after it's attached to an error doesn't change the logged value.
+// It's disabled by default.
+//
+// Slices, arrays, maps, pointers, interfaces and structs with exported fields only are copied recursively.
+// Values of basic types, [time.Time], errors (including errors built with this package) and structs without
+// pointers, slices, maps and other references (even with unexported fields) are kept as is.
+// Lazy values are computed later and aren't copied.
+// budget is the maximum number of values copied for each field including all nested elements,
+// budget <= 0 disables snapshots.
+//
+// If a value can't be copied (e.g. it contains a func, a channel or unexported slice, map or pointer)
+// or the budget is exceeded, the field value is replaced with its string representation formatted with "%+v".
+//
+// Use ErrorBuilder.WithSnapshot to override the budget for a single error.
+func SnapshotFields(budget int) {
+	atomic.StoreInt64(&snapshotBudget, int64(budget))
+}
+
+// snapshotFields returns deep copy of fields if snapshots are enabled.
+// budget is set by ErrorBuilder.WithSnapshot, 0 means the global budget set by SnapshotFields.
+func snapshotFields(fields FieldList, budget int64) FieldList {
+	if budget == 0 {
+		budget = atomic.LoadInt64(&snapshotBudget)
+	}
+	if budget <= 0 {
+		return fields
+	}
+	return snapshotList(fields, budget)
+}
+
+// snapshotList copies each field separately with its own budget, so a group is never turned into a string.
+func snapshotList(fields FieldList, budget int64) FieldList {
+	res := make(FieldList, 0, len(fields))
+	for _, f := range fields {
+		res = append(res, Field{
+			Key:   f.Key,
+			Value: snapshot(f.Value, budget),
+		})
+	}
+	return res
+}
+
+func snapshot(value any, budget int64) any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case FieldList:
+		return snapshotList(v, budget)
+	}
+	s := snapshotter{
+		budget: budget,
+	}
+	res, ok := s.copy(reflect.ValueOf(value))
+	if !ok {
+		return fmt.Sprintf("%+v", value)
+	}
+	return res.Interface()
+}
+
+type snapshotter struct {
+	budget int64
+}
+
+// copy returns deep copy of v or false if v can't be copied within the budget.
+func (s *snapshotter) copy(v reflect.Value) (reflect.Value, bool) {
+	s.budget--
+	if s.budget < 0 {
+		return reflect.Value{}, false
+	}
+	t := v.Type()
+	if t == timeType || t == lazyType || t.Implements(errorType) || !hasReferences(t) {
+		return v, true // immutable or copied by value
+	}
+	switch v.Kind() { //nolint:exhaustive // other kinds can't be copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, true
+		}
+		elem, ok := s.copy(v.Elem())
+		if !ok {
+			return reflect.Value{}, false
+		}
+		res := reflect.New(v.Type()).Elem()
+		res.Set(elem)
+		return res, true
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v, true
+		}
+		elem, ok := s.copy(v.Elem())
+		if !ok {
+			return reflect.Value{}, false
+		}
+		res := reflect.New(v.Type().Elem())
+		res.Elem().Set(elem)
+		return res, true
+	case reflect.Slice:
+		if v.IsNil() {
+			return v, true
+		}
+		return s.copyElems(v, reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+	case reflect.Array:
+		return s.copyElems(v, reflect.New(v.Type()).Elem())
+	case reflect.Map:
+		if v.IsNil() {
+			return v, true
+		}
+		res := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, ok := s.copy(iter.Key())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			elem, ok := s.copy(iter.Value())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			res.SetMapIndex(key, elem)
+		}
+		return res, true
+	case reflect.Struct:
+		res := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				return reflect.Value{}, false
+			}
+			field, ok := s.copy(v.Field(i))
+			if !ok {
+				return reflect.Value{}, false
+			}
+			res.Field(i).Set(field)
+		}
+		return res, true
+	default:
+		return reflect.Value{}, false
+	}
+}
+
+// hasReferences reports whether a value of type t may share memory with its copy.
+func hasReferences(t reflect.Type) bool {
+	switch t.Kind() { //nolint:exhaustive // other kinds are references
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return false
+	case reflect.Array:
+		return hasReferences(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasReferences(t.Field(i).Type) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// copyElems copies elements of slice or array v into res.
+func (s *snapshotter) copyElems(v reflect.Value, res reflect.Value) (reflect.Value, bool) {
+	for i := 0; i < v.Len(); i++ {
+		elem, ok := s.copy(v.Index(i))
+		if !ok {
+			return reflect.Value{}, false
+		}
+		res.Index(i).Set(elem)
+	}
+	return res, true
+}
@@ -0,0 +1,179 @@
+package errors_test
+
+import (
+	stderrors "errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maratori/errors"
+)
+
+type snapshotStruct struct {
+	Name  string
+	Tags  []string
+	Inner *snapshotStruct
+	Any   any
+}
+
+type unexportedStruct struct {
+	name string
+}
+
+type unexportedRefStruct struct {
+	tags []string
+}
+
+//nolint:paralleltest // modifies global switch
+func TestSnapshotFields(t *testing.T) {
+	errors.SnapshotFields(100)
+	t.Cleanup(func() {
+		errors.SnapshotFields(0)
+	})
+
+	t.Run("mutation after capture", func(t *testing.T) {
+		slice := []int{1, 2}
+		m := map[string][]int{"a": {1}}
+		st := &snapshotStruct{
+			Name:  "outer",
+			Tags:  []string{"tag"},
+			Inner: &snapshotStruct{Name: "inner", Tags: nil, Inner: nil, Any: []int{1}},
+			Any:   map[string]int{"a": 1},
+		}
+		arr := [2][]int{{1}, {2}}
+		err := errors.New("new err").
+			WithFields(errors.Fields{"slice": slice, "map": m}).
+			With("struct", st, "array", arr).
+			WithGroup("group", errors.Field{Key: "slice", Value: slice}).
+			E()
+
+		slice[0] = 100
+		m["a"][0] = 100
+		m["b"] = nil
+		st.Name = "changed"
+		st.Tags[0] = "changed"
+		st.Inner.Any.([]int)[0] = 100
+		st.Any.(map[string]int)["a"] = 100
+		arr[0][0] = 100
+
+		require.Equal(t, errors.FieldList{
+			{Key: "map", Value: map[string][]int{"a": {1}}},
+			{Key: "slice", Value: []int{1, 2}},
+			{Key: "struct", Value: &snapshotStruct{
+				Name:  "outer",
+				Tags:  []string{"tag"},
+				Inner: &snapshotStruct{Name: "inner", Tags: nil, Inner: nil, Any: []int{1}},
+				Any:   map[string]int{"a": 1},
+			}},
+			{Key: "array", Value: [2][]int{{1}, {2}}},
+			{Key: "group", Value: errors.FieldList{{Key: "slice", Value: []int{1, 2}}}},
+		}, errors.OrderedFields(err))
+	})
+
+	t.Run("values kept as is", func(t *testing.T) {
+		now := time.Now()
+		var nilSlice []int
+		err := errors.New("new err").With("time", now, "nil", nil, "nil slice", nilSlice, "int", 1).E()
+		require.Equal(t, errors.FieldList{
+			{Key: "time", Value: now},
+			{Key: "nil", Value: nil},
+			{Key: "nil slice", Value: nilSlice},
+			{Key: "int", Value: 1},
+		}, errors.OrderedFields(err))
+	})
+
+	t.Run("errors and value structs kept as is", func(t *testing.T) {
+		cause := errors.New("inner").WithField("key", 1).E()
+		stdErr := stderrors.New("std")
+		value := &unexportedStruct{name: "name"}
+		err := errors.New("new err").With("cause", cause, "std", stdErr, "value", value).E()
+		value.name = "changed"
+		fields := errors.FieldsFromError(err)
+		require.Equal(t, cause, fields["cause"])
+		require.Same(t, stdErr, fields["std"])
+		require.Equal(t, &unexportedStruct{name: "name"}, fields["value"])
+		got, ok := errors.Get(err, errors.NewKey[error]("cause"))
+		require.True(t, ok)
+		require.Equal(t, cause, got)
+		require.Equal(t, errors.FieldList{
+			{Key: "message", Value: "inner"},
+			{Key: "key", Value: int64(1)},
+		}, errors.Normalize(fields["cause"]))
+	})
+
+	t.Run("lazy value", func(t *testing.T) {
+		slice := []int{1}
+		err := errors.New("new err").WithField("lazy", errors.Lazy(func() any { return slice })).E()
+		slice[0] = 100
+		require.Equal(t, errors.Fields{"lazy": []int{100}}, errors.FieldsFromError(err))
+	})
+
+	t.Run("fallback for uncopyable value", func(t *testing.T) {
+		ch := make(chan int)
+		err := errors.New("new err").With(
+			"unexported", &unexportedRefStruct{tags: []string{"tag"}},
+			"func", []any{1, func() {}},
+			"chan", ch,
+			"group", errors.FieldList{{Key: "ok", Value: []int{1}}, {Key: "chan", Value: ch}},
+		).E()
+		fields := errors.FieldsFromError(err)
+		require.Equal(t, "&{tags:[tag]}", fields["unexported"])
+		require.Regexp(t, `^\[1 0x[0-9a-f]+\]$`, fields["func"])
+		require.Regexp(t, `^0x[0-9a-f]+$`, fields["chan"])
+		group, ok := fields["group"].(errors.FieldList)
+		require.True(t, ok)
+		require.Equal(t, []int{1}, group[0].Value)
+		require.IsType(t, "", group[1].Value)
+	})
+
+	t.Run("budget", func(t *testing.T) {
+		small := make([]int, 99)
+		large := make([]int, 100)
+		err := errors.New("new err").With("small", small, "large", large).E()
+		small[0] = 1
+		fields := errors.FieldsFromError(err)
+		require.Equal(t, make([]int, 99), fields["small"])
+		require.Equal(t, "["+strings.TrimSpace(strings.Repeat("0 ", 100))+"]", fields["large"])
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		st := &snapshotStruct{Name: "cycle", Tags: nil, Inner: nil, Any: nil}
+		st.Inner = st
+		err := errors.New("new err").WithField("cycle", st).E()
+		require.IsType(t, "", errors.FieldsFromError(err)["cycle"])
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		errors.SnapshotFields(0)
+		t.Cleanup(func() {
+			errors.SnapshotFields(100)
+		})
+		slice := []int{1}
+		err := errors.New("new err").WithField("slice", slice).E()
+		slice[0] = 100
+		require.Equal(t, errors.Fields{"slice": []int{100}}, errors.FieldsFromError(err))
+	})
+
+	t.Run("enabled by builder", func(t *testing.T) {
+		errors.SnapshotFields(0)
+		t.Cleanup(func() {
+			errors.SnapshotFields(100)
+		})
+		slice := []int{1}
+		base := errors.New("new err").WithField("before", slice).WithSnapshot(100)
+		err := base.Wrap("prefix").WithStack().WithField("after", slice).E()
+		other := base.WithStruct(struct{ Slice []int }{Slice: slice}).E()
+		slice[0] = 100
+		require.Equal(t, errors.Fields{"before": []int{100}, "after": []int{1}}, errors.FieldsFromError(err))
+		require.Equal(t, errors.Fields{"before": []int{100}, "Slice": []int{1}}, errors.FieldsFromError(other))
+	})
+
+	t.Run("disabled by builder", func(t *testing.T) {
+		slice := []int{1}
+		err := errors.New("new err").WithSnapshot(0).WithField("slice", slice).E()
+		slice[0] = 100
+		require.Equal(t, errors.Fields{"slice": []int{100}}, errors.FieldsFromError(err))
+	})
+}
@@ -0,0 +1,88 @@
+package errors
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const maxStackDepth = 32
+
+//nolint:gochecknoglobals // global switch is the way to enable stacks for the whole application
+var captureStacks int32
+
+// CaptureStacks enables or disables capturing of stack by New, Err, Wrap, WithFields and WithField
+// when they create a new leaf. It also enables capturing of call sites of fields reported by Provenance.
+// It's disabled by default.
+func CaptureStacks(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&captureStacks, v)
+}
+
+func stacksEnabled() bool {
+	return atomic.LoadInt32(&captureStacks) == 1
+}
+
+// Stack contains program counters of the call stack.
+// They are symbolized only when Frames or String is called.
+type Stack []uintptr
+
+// StackFromError returns stack of the first leaf or nil if it wasn't captured.
+func StackFromError(err error) Stack {
+	errs := leavesOf(err, nil)
+	if len(errs) == 0 || errs[0].stack == nil {
+		return nil
+	}
+	return *errs[0].stack
+}
+
+func (s Stack) Frames() []runtime.Frame {
+	if len(s) == 0 {
+		return nil
+	}
+	res := make([]runtime.Frame, 0, len(s))
+	frames := runtime.CallersFrames(s)
+	for {
+		frame, more := frames.Next()
+		res = append(res, frame)
+		if !more {
+			return res
+		}
+	}
+}
+
+// String returns stack in the same format as panic does.
+func (s Stack) String() string {
+	var sb strings.Builder
+	for _, frame := range s.Frames() {
+		sb.WriteString(frame.Function)
+		sb.WriteString("\n\t")
+		sb.WriteString(frame.File)
+		sb.WriteString(":")
+		sb.WriteString(strconv.Itoa(frame.Line))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// caller returns stack with the single frame of the caller of the function which called caller.
+// If skip > 0, more frames are skipped.
+func caller(skip int) Stack {
+	var pcs [1]uintptr
+	n := runtime.Callers(skip+3, pcs[:]) //nolint:mnd // skip runtime.Callers, caller and its caller
+	return append(make(Stack, 0, n), pcs[:n]...)
+}
+
+// callers returns stack of the caller of the function which called callers.
+// If skip > 0, more frames are skipped.
+func callers(skip int) *Stack {
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(skip+3, pcs[:]) //nolint:mnd // skip runtime.Callers, callers and its caller
+	stack := make(Stack, n)
+	copy(stack, pcs[:n])
+	return &stack
+}
@@ -0,0 +1,117 @@
+package errors_test
+
+import (
+	stderrors "errors"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maratori/errors"
+)
+
+//nolint:paralleltest // modifies global switch
+func TestCaptureStacks(t *testing.T) {
+	errors.CaptureStacks(true)
+	t.Cleanup(func() {
+		errors.CaptureStacks(false)
+	})
+
+	t.Run("new", func(t *testing.T) {
+		err, line := errors.New("new err").E(), currentLine()
+		requireStackAt(t, errors.StackFromError(err), line)
+	})
+
+	t.Run("err", func(t *testing.T) {
+		err, line := errors.Err(stderrors.New("new err")).E(), currentLine()
+		requireStackAt(t, errors.StackFromError(err), line)
+	})
+
+	t.Run("wrap", func(t *testing.T) {
+		err, line := errors.Wrap("prefix", stderrors.New("new err")).E(), currentLine()
+		requireStackAt(t, errors.StackFromError(err), line)
+	})
+
+	t.Run("with fields", func(t *testing.T) {
+		err, line := errors.WithFields(stderrors.New("new err"), errors.Fields{"key": "value"}).E(), currentLine()
+		requireStackAt(t, errors.StackFromError(err), line)
+	})
+
+	t.Run("with field", func(t *testing.T) {
+		err, line := errors.WithField(stderrors.New("new err"), "key", "value").E(), currentLine()
+		requireStackAt(t, errors.StackFromError(err), line)
+	})
+
+	t.Run("wrapping error with stack keeps original stack", func(t *testing.T) {
+		err, line := errors.New("new err").E(), currentLine()
+		err = errors.Wrap("prefix", err).E()
+		requireStackAt(t, errors.StackFromError(err), line)
+	})
+
+	t.Run("stack per leaf", func(t *testing.T) {
+		err1, line1 := errors.New("new err 1").E(), currentLine()
+		err2, line2 := errors.New("new err 2").E(), currentLine()
+		errs := errors.Errors(errors.Wrap("prefix", errors.Join(err1, err2)).E())
+		require.Len(t, errs, 2)
+		requireStackAt(t, errors.StackFromError(errs[0]), line1)
+		requireStackAt(t, errors.StackFromError(errs[1]), line2)
+	})
+}
+
+func TestWithStack(t *testing.T) {
+	t.Parallel()
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").E()
+		require.Nil(t, errors.StackFromError(err))
+		require.Empty(t, errors.StackFromError(err).Frames())
+		require.Empty(t, errors.StackFromError(err).String())
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		t.Parallel()
+		require.NoError(t, errors.Err(nil).WithStack().E())
+		require.Nil(t, errors.StackFromError(nil))
+	})
+
+	t.Run("with stack", func(t *testing.T) {
+		t.Parallel()
+		err, line := errors.New("new err").WithStack().E(), currentLine()
+		requireStackAt(t, errors.StackFromError(err), line)
+	})
+
+	t.Run("inner stack has priority", func(t *testing.T) {
+		t.Parallel()
+		err1, line1 := errors.New("new err 1").WithStack().E(), currentLine()
+		err2 := errors.New("new err 2").E()
+		err, line := errors.Join(err1, err2), currentLine()
+		errs := errors.Errors(errors.Err(err).WithStack().E())
+		require.Len(t, errs, 2)
+		requireStackAt(t, errors.StackFromError(errs[0]), line1)
+		requireStackAt(t, errors.StackFromError(errs[1]), line+1)
+	})
+
+	t.Run("string", func(t *testing.T) {
+		t.Parallel()
+		err, line := errors.New("new err").WithStack().E(), currentLine()
+		stack := errors.StackFromError(err)
+		frame := stack.Frames()[0]
+		require.Contains(t, stack.String(), frame.Function+"\n\t"+frame.File+":"+strconv.Itoa(line)+"\n")
+	})
+}
+
+func requireStackAt(t *testing.T, stack errors.Stack, line int) {
+	t.Helper()
+	frames := stack.Frames()
+	require.NotEmpty(t, frames)
+	require.Equal(t, line, frames[0].Line)
+	_, file, _, _ := runtime.Caller(0)
+	require.Equal(t, file, frames[0].File)
+}
+
+func currentLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
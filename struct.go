@@ -0,0 +1,240 @@
+package errors
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	structTag = "errfield"
+	// badStructKey is the key of a value passed to WithStruct that is not a struct, similar to badKey.
+	badStructKey = "!BADSTRUCT"
+	// maxStructDepth limits expansion of nested structs, deeper structs are added as values.
+	// It prevents infinite recursion on cyclic values.
+	maxStructDepth = 8
+)
+
+//nolint:gochecknoglobals // cache of reflection metadata shared by all errors
+var structFieldsCache sync.Map // reflect.Type -> []structField
+
+//nolint:gochecknoglobals // types are compared on each nested struct
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// WithStruct adds exported fields of struct v (or pointer to struct) in order of declaration.
+// The key of a field is the name from `errfield:"name"` tag or the name of the field if there is no tag.
+// Tag options:
+//   - `errfield:"-"` skips the field;
+//   - `errfield:"name,omitempty"` skips the field with zero value, empty string, slice or map.
+//
+// A field of a struct type (or pointer to struct) with exported fields is added as a group (see Group),
+// so its fields are prefixed with its key when groups are flattened, e.g. "customer.id".
+// Fields of an embedded struct without a tag are added as fields of the outer struct.
+// Keys are unique: a field of the struct wins over a field of an embedded struct with the same key,
+// a tagged field wins over an untagged one, see [encoding/json] for details.
+// Structs implementing [json.Marshaler] or [encoding.TextMarshaler] are added as values.
+// Nil v or nil pointer adds nothing. If v is not a struct or a pointer to struct, it's added with "!BADSTRUCT" key,
+// the same way as With adds a value without a key.
+//
+//	type Order struct {
+//		ID         string `errfield:"order_id"`
+//		CustomerID string `errfield:"customer_id"`
+//		Comment    string `errfield:"-"`
+//	}
+//
+//	errors.New("can't pay order").WithStruct(order)
+//
+// Reflection metadata is cached per type, so repeated calls are cheap.
+func (e *ErrorBuilder) WithStruct(v any) *ErrorBuilder {
+	return e.withFields(structFieldsFromValue(v), 1)
+}
+
+// WithStruct is shorthand for Err(err).WithStruct(v), see ErrorBuilder.WithStruct.
+func WithStruct(err error, v any) *ErrorBuilder {
+	return build(err, 1).withFields(structFieldsFromValue(v), 1)
+}
+
+type structField struct {
+	index     int
+	name      string
+	tagged    bool // name is set by the tag
+	omitEmpty bool
+	nested    bool // struct or pointer to struct expanded as a group
+	inline    bool // embedded struct without a tag expanded into the outer struct
+}
+
+func structFieldsFromValue(v any) FieldList {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	if !value.IsValid() { // nil or nil pointer
+		return nil
+	}
+	if value.Kind() != reflect.Struct {
+		return FieldList{{Key: badStructKey, Value: v}}
+	}
+	return structFields(value, 0)
+}
+
+// structFields returns fields of struct value with unique keys.
+func structFields(value reflect.Value, depth int) FieldList {
+	return dominantFields(appendStructFields(nil, value, depth, 0))
+}
+
+// structValue is a field of a struct or of an embedded struct.
+type structValue struct {
+	field  Field
+	level  int // embedding level, 0 for fields of the struct itself
+	tagged bool
+}
+
+func appendStructFields(res []structValue, value reflect.Value, depth int, level int) []structValue {
+	for _, f := range cachedStructFields(value.Type()) {
+		v := value.Field(f.index)
+		if f.omitEmpty && isEmptyValue(v) {
+			continue
+		}
+		if (f.nested || f.inline) && depth < maxStructDepth {
+			elem := v
+			if elem.Kind() == reflect.Pointer {
+				elem = elem.Elem()
+			}
+			switch {
+			case !elem.IsValid(): // nil pointer
+				if f.inline {
+					continue
+				}
+			case f.inline:
+				res = appendStructFields(res, elem, depth+1, level+1)
+				continue
+			default:
+				res = append(res, structValue{
+					field:  Group(f.name, structFields(elem, depth+1)...),
+					level:  level,
+					tagged: f.tagged,
+				})
+				continue
+			}
+		}
+		res = append(res, structValue{
+			field: Field{
+				Key:   f.name,
+				Value: v.Interface(),
+			},
+			level:  level,
+			tagged: f.tagged,
+		})
+	}
+	return res
+}
+
+// dominantFields resolves duplicated keys the same way as [encoding/json] does for embedded structs:
+// a shallower field wins over a promoted one, a tagged field wins over an untagged one at the same level.
+// Remaining duplicates (e.g. two fields with the same tag) are resolved by uniqueFields, so the last one wins.
+func dominantFields(values []structValue) FieldList {
+	fields := make([]Field, 0, len(values))
+	for _, v := range values {
+		if !isDominated(v, values) {
+			fields = append(fields, v.field)
+		}
+	}
+	return uniqueFields(fields)
+}
+
+func isDominated(v structValue, values []structValue) bool {
+	for _, other := range values {
+		if other.field.Key != v.field.Key {
+			continue
+		}
+		if other.level < v.level || other.level == v.level && other.tagged && !v.tagged {
+			return true
+		}
+	}
+	return false
+}
+
+func cachedStructFields(t reflect.Type) []structField {
+	if cached, ok := structFieldsCache.Load(t); ok {
+		return cached.([]structField) //nolint:forcetypeassert // only []structField is stored
+	}
+	cached, _ := structFieldsCache.LoadOrStore(t, parseStructFields(t))
+	return cached.([]structField) //nolint:forcetypeassert // only []structField is stored
+}
+
+func parseStructFields(t reflect.Type) []structField {
+	res := make([]structField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag, hasTag := sf.Tag.Lookup(structTag)
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		tagged := name != ""
+		if !tagged {
+			name = sf.Name
+		}
+		nested := isNestedStruct(sf.Type)
+		inline := nested && sf.Anonymous && !hasTag
+		res = append(res, structField{
+			index:     i,
+			name:      name,
+			tagged:    tagged,
+			omitEmpty: hasOption(opts, "omitempty"),
+			nested:    nested && !inline,
+			inline:    inline,
+		})
+	}
+	return res
+}
+
+func hasOption(opts string, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// isNestedStruct reports whether t is a struct or a pointer to struct which fields should be expanded.
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for _, marshaler := range []reflect.Type{jsonMarshalerType, textMarshalerType} {
+		if t.Implements(marshaler) || reflect.PointerTo(t).Implements(marshaler) {
+			return false
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmptyValue is similar to omitempty option of [encoding/json], but zero structs are empty as well.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() { //nolint:exhaustive // other kinds are checked by IsZero
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
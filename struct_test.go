@@ -0,0 +1,171 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maratori/errors"
+)
+
+type Customer struct {
+	ID   string `errfield:"id"`
+	Name string `errfield:"-"`
+}
+
+type Audit struct {
+	CreatedBy string `errfield:"created_by,omitempty"`
+}
+
+type Order struct {
+	Audit
+	ID        string            `errfield:"order_id"`
+	Customer  Customer          `errfield:"customer"`
+	Courier   *Customer         `errfield:"courier,omitempty"`
+	Items     []string          `errfield:"items,omitempty"`
+	Amount    int               `errfield:",omitempty"`
+	Status    string            // without tag
+	CreatedAt time.Time         `errfield:"created_at"`
+	Raw       json.RawMessage   `errfield:"raw,omitempty"`
+	Meta      map[string]string `errfield:"meta,omitempty"`
+	secret    string
+}
+
+type Entity struct {
+	ID   string `errfield:"id"`
+	Kind string
+}
+
+type Labeled struct {
+	Label string `errfield:"Kind"`
+}
+
+type Account struct {
+	Entity
+	Labeled
+	ID    string `errfield:"id"`
+	Login string `errfield:"x"`
+	Email string `errfield:"x"`
+}
+
+type Node struct {
+	Name string `errfield:"name"`
+	Next *Node  `errfield:"next"`
+}
+
+func TestWithStruct(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithStruct(nil).WithStruct((*Order)(nil)).E()
+		require.Empty(t, errors.OrderedFields(err))
+		require.Nil(t, errors.WithStruct(nil, Order{}))
+	})
+
+	t.Run("not a struct", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithStruct(map[string]any{"key": "value"}).E()
+		require.Equal(t, errors.FieldList{
+			{Key: "!BADSTRUCT", Value: map[string]any{"key": "value"}},
+		}, errors.OrderedFields(err))
+		n := 1
+		err = errors.WithStruct(errors.New("new err").E(), &n).E()
+		require.Equal(t, errors.Fields{"!BADSTRUCT": &n}, errors.FieldsFromError(err))
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		t.Parallel()
+		createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		order := Order{
+			Audit:     Audit{CreatedBy: "admin"},
+			ID:        "o1",
+			Customer:  Customer{ID: "c1", Name: "John"},
+			Courier:   nil,
+			Items:     nil,
+			Amount:    0,
+			Status:    "new",
+			CreatedAt: createdAt,
+			Raw:       nil,
+			Meta:      map[string]string{},
+			secret:    "secret",
+		}
+		err := errors.New("new err").WithStruct(order).E()
+		require.Equal(t, errors.FieldList{
+			{Key: "created_by", Value: "admin"},
+			{Key: "order_id", Value: "o1"},
+			{Key: "customer", Value: errors.FieldList{{Key: "id", Value: "c1"}}},
+			{Key: "Status", Value: "new"},
+			{Key: "created_at", Value: createdAt},
+		}, errors.OrderedFields(err))
+	})
+
+	t.Run("pointer and nested pointer", func(t *testing.T) {
+		t.Parallel()
+		order := &Order{
+			Audit:     Audit{CreatedBy: ""},
+			ID:        "o1",
+			Customer:  Customer{ID: "c1", Name: ""},
+			Courier:   &Customer{ID: "c2", Name: ""},
+			Items:     []string{"i1"},
+			Amount:    10,
+			Status:    "",
+			CreatedAt: time.Time{},
+			Raw:       json.RawMessage(`{}`),
+			Meta:      map[string]string{"k": "v"},
+			secret:    "",
+		}
+		err := errors.WithStruct(errors.New("new err").E(), order).E()
+		require.Equal(t, errors.FieldList{
+			{Key: "order_id", Value: "o1"},
+			{Key: "customer", Value: errors.FieldList{{Key: "id", Value: "c1"}}},
+			{Key: "courier", Value: errors.FieldList{{Key: "id", Value: "c2"}}},
+			{Key: "items", Value: []string{"i1"}},
+			{Key: "Amount", Value: 10},
+			{Key: "Status", Value: ""},
+			{Key: "created_at", Value: time.Time{}},
+			{Key: "raw", Value: json.RawMessage(`{}`)},
+			{Key: "meta", Value: map[string]string{"k": "v"}},
+		}, errors.OrderedFields(err))
+		flat := errors.OrderedFields(err).Flatten(".")
+		require.Equal(t, errors.Field{Key: "customer.id", Value: "c1"}, flat[1])
+		require.Equal(t, errors.Field{Key: "courier.id", Value: "c2"}, flat[2])
+	})
+
+	t.Run("cyclic value", func(t *testing.T) {
+		t.Parallel()
+		node := &Node{Name: "n", Next: nil}
+		node.Next = node
+		fields := errors.OrderedFields(errors.New("new err").WithStruct(node).E())
+		for i := 0; i < 8; i++ {
+			require.Equal(t, "name", fields[0].Key)
+			require.Equal(t, "next", fields[1].Key)
+			group, ok := fields[1].Value.(errors.FieldList)
+			require.True(t, ok)
+			fields = group
+		}
+		require.Equal(t, errors.FieldList{{Key: "name", Value: "n"}, {Key: "next", Value: node}}, fields)
+	})
+
+	t.Run("key collisions", func(t *testing.T) {
+		t.Parallel()
+		account := Account{
+			Entity:  Entity{ID: "inner", Kind: "untagged"},
+			Labeled: Labeled{Label: "tagged"},
+			ID:      "outer",
+			Login:   "login",
+			Email:   "email",
+		}
+		err := errors.New("new err").WithStruct(account).E()
+		require.Equal(t, errors.FieldList{
+			{Key: "Kind", Value: "tagged"},
+			{Key: "id", Value: "outer"},
+			{Key: "x", Value: "email"},
+		}, errors.OrderedFields(err))
+		value, ok := errors.Get(err, errors.NewKey[string]("x"))
+		require.True(t, ok)
+		require.Equal(t, errors.FieldsFromError(err)["x"], value)
+	})
+}
@@ -0,0 +1,20 @@
+module github.com/maratori/errors/zaperrors
+
+go 1.18
+
+require (
+	github.com/maratori/errors v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.7.0
+	go.uber.org/zap v1.22.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.uber.org/atomic v1.7.0 // indirect
+	go.uber.org/multierr v1.6.0 // indirect
+	golang.org/x/xerrors v0.0.0-20240903120638-7835f813f4da // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/maratori/errors => ../ // develop against the local core module, see CONTRIBUTING.md
@@ -0,0 +1,167 @@
+// Package zaperrors provides zap fields and marshalers for errors with fields.
+package zaperrors
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/maratori/errors"
+)
+
+const (
+	messageKey = "message"
+	pathKey    = "path"
+	leavesKey  = "leaves"
+)
+
+// Option configures how errors are marshaled.
+type Option func(*options)
+
+type options struct {
+	path           bool
+	groupSeparator string
+}
+
+// WithPath replaces error message with the constant message of the leaf without prefixes
+// and adds prefixes as an ordered array under "path" key.
+// The first leaf is used for joined error, the same as for fields.
+func WithPath() Option {
+	return func(o *options) {
+		o.path = true
+	}
+}
+
+// WithGroupSeparator flattens groups of fields (see [errors.Group]) into keys joined with sep,
+// e.g. "db.table" for ".". By default, groups are rendered as nested objects.
+func WithGroupSeparator(sep string) Option {
+	return func(o *options) {
+		o.groupSeparator = sep
+	}
+}
+
+// Error is shorthand for NamedError("error", err, opts...).
+func Error(err error, opts ...Option) zap.Field {
+	return NamedError("error", err, opts...)
+}
+
+// NamedError returns zap field with object built by Object.
+// If err is nil, the field is skipped.
+func NamedError(key string, err error, opts ...Option) zap.Field {
+	if err == nil {
+		return zap.Skip()
+	}
+	return zap.Object(key, Object(err, opts...))
+}
+
+// Object returns marshaler that writes error message, fields from [errors.FieldsFromError]
+// and array of leaves from [errors.Errors] if there is more than one leaf.
+// Fields named "message", "path" or "leaves" are renamed with [errors.FieldList.RenameReserved].
+func Object(err error, opts ...Option) zapcore.ObjectMarshaler {
+	return object{
+		err:  err,
+		opts: newOptions(opts),
+	}
+}
+
+// Leaves returns marshaler that writes an object for each leaf from [errors.Errors].
+func Leaves(err error, opts ...Option) zapcore.ArrayMarshaler {
+	return leaves{
+		leaves: leavesOf(err),
+		opts:   newOptions(opts),
+	}
+}
+
+func newOptions(opts []Option) options {
+	res := options{
+		path:           false,
+		groupSeparator: "",
+	}
+	for _, opt := range opts {
+		opt(&res)
+	}
+	return res
+}
+
+type object struct {
+	err  error
+	opts options
+}
+
+func (o object) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	if o.err == nil {
+		return nil
+	}
+	all := leavesOf(o.err)
+	o.opts.addMessage(enc, o.err, all[0])
+	o.opts.addFields(enc, all[0].Fields)
+	if len(all) > 1 {
+		return enc.AddArray(leavesKey, leaves{
+			leaves: all,
+			opts:   o.opts,
+		})
+	}
+	return nil
+}
+
+// leavesOf returns leaves sharing path and fields with the tree to avoid copying them on each log call.
+func leavesOf(err error) []errors.Leaf {
+	var res []errors.Leaf
+	errors.RangeLeaves(err, func(leaf errors.Leaf) bool {
+		res = append(res, leaf)
+		return true
+	})
+	return res
+}
+
+type leaves struct {
+	leaves []errors.Leaf
+	opts   options
+}
+
+func (l leaves) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, leaf := range l.leaves {
+		err := enc.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			l.opts.addMessage(enc, leaf, leaf)
+			l.opts.addFields(enc, leaf.Fields)
+			return nil
+		}))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addMessage adds message of err or, if path option is set, message and path of leaf.
+func (o options) addMessage(enc zapcore.ObjectEncoder, err error, leaf errors.Leaf) {
+	if !o.path {
+		enc.AddString(messageKey, err.Error())
+		return
+	}
+	enc.AddString(messageKey, leaf.Message)
+	if len(leaf.Path) > 0 {
+		zap.Strings(pathKey, leaf.Path).AddTo(enc)
+	}
+}
+
+// addFields normalizes each value while adding it, fields are copied only to be flattened or renamed.
+func (o options) addFields(enc zapcore.ObjectEncoder, fields errors.FieldList) {
+	if o.groupSeparator != "" {
+		fields = errors.NormalizeFields(fields).Flatten(o.groupSeparator)
+	}
+	addFields(enc, fields.RenameReserved(messageKey, pathKey, leavesKey))
+}
+
+func addFields(enc zapcore.ObjectEncoder, fields errors.FieldList) {
+	for _, f := range fields {
+		value := errors.Normalize(f.Value)
+		if group, ok := value.(errors.FieldList); ok {
+			zap.Object(f.Key, zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+				addFields(enc, group)
+				return nil
+			})).AddTo(enc)
+			continue
+		}
+		zap.Any(f.Key, value).AddTo(enc)
+	}
+}
@@ -0,0 +1,154 @@
+package zaperrors_test
+
+import (
+	"bytes"
+	stderrors "errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/maratori/errors"
+	"github.com/maratori/errors/zaperrors"
+)
+
+func TestError(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil error is skipped", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger()
+		logger.Error("failed", zaperrors.Error(nil))
+		require.JSONEq(t, `{"msg":"failed"}`, buf.String())
+	})
+
+	t.Run("foreign error", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger()
+		logger.Error("failed", zaperrors.Error(stderrors.New("new err")))
+		require.JSONEq(t, `{"msg":"failed","error":{"message":"new err"}}`, buf.String())
+	})
+
+	t.Run("wrapped error with fields", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithField("key2", 2).Wrap("prefix").WithField("key1", "value1").E()
+		logger, buf := newLogger()
+		logger.Error("failed", zaperrors.NamedError("err", err))
+		require.JSONEq(t,
+			`{"msg":"failed","err":{"message":"prefix: new err","key1":"value1","key2":2}}`,
+			buf.String(),
+		)
+	})
+
+	t.Run("reserved keys", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").With("message", "field", "path", 1, "leaves", 2).E()
+		logger, buf := newLogger()
+		logger.Error("failed", zaperrors.Error(err, zaperrors.WithPath()))
+		require.Equal(t,
+			`{"msg":"failed","error":{"message":"new err","message#1":"field","path#1":1,"leaves#1":2}}`+"\n",
+			buf.String(),
+		)
+	})
+
+	t.Run("joined errors", func(t *testing.T) {
+		t.Parallel()
+		err := errors.Wrap("prefix", errors.Join(
+			errors.New("new err 1").WithField("key1", "value1").E(),
+			errors.New("new err 2").WithField("key2", "value2").E(),
+		)).WithField("key3", "value3").E()
+		logger, buf := newLogger()
+		logger.Error("failed", zaperrors.Error(err))
+		require.JSONEq(t, `{"msg":"failed","error":{
+			"message":"prefix: new err 1\nnew err 2",
+			"key1":"value1",
+			"key3":"value3",
+			"leaves":[
+				{"message":"prefix: new err 1","key1":"value1","key3":"value3"},
+				{"message":"prefix: new err 2","key2":"value2","key3":"value3"}
+			]
+		}}`, buf.String())
+	})
+
+	t.Run("path", func(t *testing.T) {
+		t.Parallel()
+		err := errors.Wrap("prefix1", errors.Join(
+			errors.New("new err 1").Wrap("prefix2").WithField("key1", "value1").E(),
+			stderrors.New("new err 2"),
+		)).E()
+		logger, buf := newLogger()
+		logger.Error("failed", zaperrors.Error(err, zaperrors.WithPath()))
+		require.JSONEq(t, `{"msg":"failed","error":{
+			"message":"new err 1",
+			"path":["prefix1","prefix2"],
+			"key1":"value1",
+			"leaves":[
+				{"message":"new err 1","path":["prefix1","prefix2"],"key1":"value1"},
+				{"message":"new err 2","path":["prefix1"]}
+			]
+		}}`, buf.String())
+	})
+
+	t.Run("groups", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").
+			WithGroup("db", errors.Field{Key: "table", Value: "orders"}, errors.Group("query",
+				errors.Field{Key: "id", Value: 1},
+			)).
+			E()
+		logger, buf := newLogger()
+		logger.Error("failed", zaperrors.Error(err))
+		require.Equal(t,
+			`{"msg":"failed","error":{"message":"new err","db":{"table":"orders","query":{"id":1}}}}`+"\n",
+			buf.String(),
+		)
+
+		logger, buf = newLogger()
+		logger.Error("failed", zaperrors.Error(err, zaperrors.WithGroupSeparator(".")))
+		require.JSONEq(t,
+			`{"msg":"failed","error":{"message":"new err","db.table":"orders","db.query.id":1}}`,
+			buf.String(),
+		)
+	})
+
+	t.Run("normalized values", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").
+			With("cause", errors.New("inner").WithField("id", 1).E(), "raw", []byte("bytes"), "ids", []uint{1, 2}).
+			E()
+		logger, buf := newLogger()
+		logger.Error("failed", zaperrors.Error(err))
+		require.JSONEq(t,
+			`{"msg":"failed","error":{
+			"message":"new err",
+			"cause":{"message":"inner","id":1},
+			"raw":"bytes",
+			"ids":[1,2]
+		}}`,
+			buf.String(),
+		)
+	})
+
+	t.Run("leaves array", func(t *testing.T) {
+		t.Parallel()
+		err := errors.Join(
+			errors.New("new err 1").WithField("key1", "value1").E(),
+			stderrors.New("new err 2"),
+		)
+		logger, buf := newLogger()
+		logger.Error("failed", zap.Array("errors", zaperrors.Leaves(err)))
+		require.JSONEq(t, `{"msg":"failed","errors":[
+			{"message":"new err 1","key1":"value1"},
+			{"message":"new err 2"}
+		]}`, buf.String())
+	})
+}
+
+func newLogger() (*zap.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	encoder := zapcore.NewJSONEncoder(zapcore.EncoderConfig{ //nolint:exhaustruct // only message is needed
+		MessageKey: "msg",
+	})
+	return zap.New(zapcore.NewCore(encoder, zapcore.AddSync(&buf), zapcore.DebugLevel)), &buf
+}
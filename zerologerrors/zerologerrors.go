@@ -0,0 +1,246 @@
+// Package zerologerrors provides zerolog marshalers for errors with fields.
+//
+// Errors are rendered as nested objects when MarshalError is used as zerolog.ErrorMarshalFunc:
+//
+//	zerolog.ErrorMarshalFunc = zerologerrors.MarshalError
+//	log.Error().Err(err).Msg("failed")
+//
+// Use MarshalErrorFunc to pass options, e.g. zerologerrors.MarshalErrorFunc(zerologerrors.WithPath()).
+//
+// Use Err to put fields at the top level of the event instead:
+//
+//	zerologerrors.Err(log.Error(), err).Msg("failed")
+//
+// Pass WithPath to render the constant message of the leaf and its prefixes separately:
+//
+//	zerologerrors.Err(log.Error(), err, zerologerrors.WithPath()).Msg("failed")
+//
+// Stack captured by [errors.ErrorBuilder.WithStack] or [errors.CaptureStacks] is rendered
+// when MarshalStack is used as zerolog.ErrorStackMarshaler:
+//
+//	zerolog.ErrorStackMarshaler = zerologerrors.MarshalStack
+//	log.Error().Stack().Err(err).Msg("failed")
+//
+// Fields colliding with keys added next to them ("message", "path" and "leaves" in objects,
+// zerolog.ErrorFieldName, its path and "leaves" for Err) are renamed with [errors.FieldList.RenameReserved].
+package zerologerrors
+
+import (
+	"github.com/rs/zerolog"
+
+	"github.com/maratori/errors"
+)
+
+const (
+	messageKey = "message"
+	pathKey    = "path"
+	leavesKey  = "leaves"
+	funcKey    = "func"
+	fileKey    = "file"
+	lineKey    = "line"
+)
+
+// Option configures how errors are marshaled.
+type Option func(*options)
+
+type options struct {
+	path           bool
+	groupSeparator string
+}
+
+// WithPath replaces error message with the constant message of the leaf without prefixes
+// and adds prefixes as an ordered array under "path" key ("error.path" for Err).
+// The first leaf is used for joined error, the same as for fields.
+func WithPath() Option {
+	return func(o *options) {
+		o.path = true
+	}
+}
+
+// WithGroupSeparator flattens groups of fields (see [errors.Group]) into keys joined with sep,
+// e.g. "db.table" for ".". By default, groups are rendered as nested objects.
+func WithGroupSeparator(sep string) Option {
+	return func(o *options) {
+		o.groupSeparator = sep
+	}
+}
+
+func newOptions(opts []Option) options {
+	res := options{
+		path:           false,
+		groupSeparator: "",
+	}
+	for _, opt := range opts {
+		opt(&res)
+	}
+	return res
+}
+
+// MarshalError has signature of zerolog.ErrorMarshalFunc and returns result of Object.
+func MarshalError(err error) any {
+	return marshalError(err, newOptions(nil))
+}
+
+// MarshalErrorFunc returns function with signature of zerolog.ErrorMarshalFunc that returns result of Object
+// with opts, e.g.:
+//
+//	zerolog.ErrorMarshalFunc = zerologerrors.MarshalErrorFunc(zerologerrors.WithPath())
+func MarshalErrorFunc(opts ...Option) func(error) any {
+	o := newOptions(opts)
+	return func(err error) any {
+		return marshalError(err, o)
+	}
+}
+
+func marshalError(err error, opts options) any {
+	if err == nil {
+		return nil
+	}
+	return object{
+		err:  err,
+		opts: opts,
+	}
+}
+
+// MarshalStack has signature of zerolog.ErrorStackMarshaler and returns array of frames
+// from [errors.StackFromError] or nil if stack wasn't captured.
+func MarshalStack(err error) any {
+	stack := errors.StackFromError(err)
+	if stack == nil {
+		return nil
+	}
+	frames := stack.Frames()
+	res := make([]map[string]any, 0, len(frames))
+	for _, frame := range frames {
+		res = append(res, map[string]any{
+			funcKey: frame.Function,
+			fileKey: frame.File,
+			lineKey: frame.Line,
+		})
+	}
+	return res
+}
+
+// Object returns marshaler that writes error message, fields from [errors.FieldsFromError]
+// and array of leaves from [errors.Errors] if there is more than one leaf.
+func Object(err error, opts ...Option) zerolog.LogObjectMarshaler {
+	return object{
+		err:  err,
+		opts: newOptions(opts),
+	}
+}
+
+// Leaves returns marshaler that writes an object for each leaf from [errors.Errors].
+func Leaves(err error, opts ...Option) zerolog.LogArrayMarshaler {
+	return leaves{
+		leaves: errors.Leaves(err),
+		opts:   newOptions(opts),
+	}
+}
+
+// Err adds error message under zerolog.ErrorFieldName, fields from [errors.FieldsFromError]
+// at the top level of the event and array of leaves if there is more than one leaf.
+func Err(e *zerolog.Event, err error, opts ...Option) *zerolog.Event {
+	if err == nil {
+		return e
+	}
+	o := newOptions(opts)
+	all := errors.Leaves(err)
+	o.addMessage(e, zerolog.ErrorFieldName, zerolog.ErrorFieldName+"."+pathKey, err, all[0])
+	e.Fields(o.keysAndValues(all[0].Fields, zerolog.ErrorFieldName, zerolog.ErrorFieldName+"."+pathKey, leavesKey))
+	if len(all) > 1 {
+		e.Array(leavesKey, leaves{
+			leaves: all,
+			opts:   o,
+		})
+	}
+	return e
+}
+
+type object struct {
+	err  error
+	opts options
+}
+
+func (o object) MarshalZerologObject(e *zerolog.Event) {
+	if o.err == nil {
+		return
+	}
+	all := errors.Leaves(o.err)
+	o.opts.addMessage(e, messageKey, pathKey, o.err, all[0])
+	e.Fields(o.opts.keysAndValues(all[0].Fields, messageKey, pathKey, leavesKey))
+	if len(all) > 1 {
+		e.Array(leavesKey, leaves{
+			leaves: all,
+			opts:   o.opts,
+		})
+	}
+}
+
+type leaves struct {
+	leaves []errors.Leaf
+	opts   options
+}
+
+func (l leaves) MarshalZerologArray(a *zerolog.Array) {
+	for _, leaf := range l.leaves {
+		a.Object(leafObject{
+			leaf: leaf,
+			opts: l.opts,
+		})
+	}
+}
+
+type leafObject struct {
+	leaf errors.Leaf
+	opts options
+}
+
+func (l leafObject) MarshalZerologObject(e *zerolog.Event) {
+	l.opts.addMessage(e, messageKey, pathKey, l.leaf, l.leaf)
+	e.Fields(l.opts.keysAndValues(l.leaf.Fields, messageKey, pathKey, leavesKey))
+}
+
+// addMessage adds message of err or, if path option is set, message and path of leaf.
+func (o options) addMessage(e *zerolog.Event, key, pathKey string, err error, leaf errors.Leaf) {
+	if !o.path {
+		e.Str(key, err.Error())
+		return
+	}
+	e.Str(key, leaf.Message)
+	if len(leaf.Path) > 0 {
+		e.Strs(pathKey, leaf.Path)
+	}
+}
+
+// keysAndValues converts fields into the list accepted by [zerolog.Event.Fields] to keep their order.
+// Fields with reserved keys are renamed with [errors.FieldList.RenameReserved].
+func (o options) keysAndValues(fields errors.FieldList, reserved ...string) []any {
+	fields = errors.NormalizeFields(fields)
+	if o.groupSeparator != "" {
+		fields = fields.Flatten(o.groupSeparator)
+	}
+	return keysAndValues(fields.RenameReserved(reserved...))
+}
+
+func keysAndValues(fields errors.FieldList) []any {
+	res := make([]any, 0, 2*len(fields)) //nolint:mnd // key and value
+	for _, f := range fields {
+		if group, ok := f.Value.(errors.FieldList); ok {
+			res = append(res, f.Key, groupObject{
+				fields: group,
+			})
+			continue
+		}
+		res = append(res, f.Key, f.Value)
+	}
+	return res
+}
+
+type groupObject struct {
+	fields errors.FieldList
+}
+
+func (g groupObject) MarshalZerologObject(e *zerolog.Event) {
+	e.Fields(keysAndValues(g.fields))
+}
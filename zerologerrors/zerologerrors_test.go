@@ -0,0 +1,294 @@
+package zerologerrors_test
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maratori/errors"
+	"github.com/maratori/errors/zerologerrors"
+)
+
+//nolint:paralleltest // modifies global zerolog.ErrorMarshalFunc
+func TestMarshalError(t *testing.T) {
+	original := zerolog.ErrorMarshalFunc
+	zerolog.ErrorMarshalFunc = zerologerrors.MarshalError
+	t.Cleanup(func() {
+		zerolog.ErrorMarshalFunc = original
+	})
+
+	err := errors.New("new err").WithField("key2", 2).Wrap("prefix").WithField("key1", "value1").E()
+	logger, buf := newLogger()
+	logger.Log().Err(err).Msg("failed")
+	require.JSONEq(t,
+		`{"message":"failed","error":{"message":"prefix: new err","key1":"value1","key2":2}}`,
+		buf.String(),
+	)
+
+	require.Nil(t, zerologerrors.MarshalError(nil))
+}
+
+//nolint:paralleltest // modifies global zerolog.ErrorMarshalFunc
+func TestMarshalErrorFunc(t *testing.T) {
+	original := zerolog.ErrorMarshalFunc
+	zerolog.ErrorMarshalFunc = zerologerrors.MarshalErrorFunc(zerologerrors.WithPath())
+	t.Cleanup(func() {
+		zerolog.ErrorMarshalFunc = original
+	})
+
+	err := errors.New("new err").WithField("key2", 2).Wrap("prefix").WithField("key1", "value1").E()
+	logger, buf := newLogger()
+	logger.Log().Err(err).Msg("failed")
+	require.JSONEq(t,
+		`{"message":"failed","error":{"message":"new err","path":["prefix"],"key1":"value1","key2":2}}`,
+		buf.String(),
+	)
+
+	require.Nil(t, zerologerrors.MarshalErrorFunc()(nil))
+}
+
+func TestObject(t *testing.T) {
+	t.Parallel()
+
+	t.Run("foreign error", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger()
+		logger.Log().Object("error", zerologerrors.Object(stderrors.New("new err"))).Msg("failed")
+		require.JSONEq(t, `{"message":"failed","error":{"message":"new err"}}`, buf.String())
+	})
+
+	t.Run("nil error", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger()
+		logger.Log().Object("error", zerologerrors.Object(nil)).Msg("failed")
+		require.JSONEq(t, `{"message":"failed","error":{}}`, buf.String())
+	})
+
+	t.Run("joined errors", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger()
+		logger.Log().Object("error", zerologerrors.Object(joined())).Msg("failed")
+		require.JSONEq(t, `{"message":"failed","error":{
+			"message":"prefix: new err 1\nnew err 2",
+			"key1":"value1",
+			"key3":"value3",
+			"leaves":[
+				{"message":"prefix: new err 1","key1":"value1","key3":"value3"},
+				{"message":"prefix: new err 2","key2":"value2","key3":"value3"}
+			]
+		}}`, buf.String())
+	})
+}
+
+func TestLeaves(t *testing.T) {
+	t.Parallel()
+	err := errors.Join(
+		errors.New("new err 1").WithField("key1", "value1").E(),
+		stderrors.New("new err 2"),
+	)
+	logger, buf := newLogger()
+	logger.Log().Array("errors", zerologerrors.Leaves(err)).Msg("failed")
+	require.JSONEq(t, `{"message":"failed","errors":[
+		{"message":"new err 1","key1":"value1"},
+		{"message":"new err 2"}
+	]}`, buf.String())
+}
+
+func TestErr(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil error", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger()
+		zerologerrors.Err(logger.Log(), nil).Msg("failed")
+		require.JSONEq(t, `{"message":"failed"}`, buf.String())
+	})
+
+	t.Run("top level fields", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").WithField("key2", 2).Wrap("prefix").WithField("key1", "value1").E()
+		logger, buf := newLogger()
+		zerologerrors.Err(logger.Log(), err).Msg("failed")
+		require.JSONEq(t,
+			`{"message":"failed","error":"prefix: new err","key1":"value1","key2":2}`,
+			buf.String(),
+		)
+	})
+
+	t.Run("reserved keys", func(t *testing.T) {
+		t.Parallel()
+		err := errors.New("new err").With("error", "field", "error.path", 1, "leaves", 2).E()
+		logger, buf := newLogger()
+		zerologerrors.Err(logger.Log(), err).Msg("failed")
+		logger.Log().Object("error", zerologerrors.Object(errors.WithField(err, "message", 3).E())).Msg("failed")
+		lines := strings.Split(buf.String(), "\n")
+		require.Equal(t,
+			`{"error":"new err","error#1":"field","error.path#1":1,"leaves#1":2,"message":"failed"}`,
+			lines[0],
+		)
+		require.Equal(t, `{"error":{`+
+			`"message":"new err","error":"field","error.path":1,"leaves#1":2,"message#1":3`+
+			`},"message":"failed"}`,
+			lines[1],
+		)
+	})
+
+	t.Run("joined errors", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger()
+		zerologerrors.Err(logger.Log(), joined()).Msg("failed")
+		require.JSONEq(t, `{
+			"message":"failed",
+			"error":"prefix: new err 1\nnew err 2",
+			"key1":"value1",
+			"key3":"value3",
+			"leaves":[
+				{"message":"prefix: new err 1","key1":"value1","key3":"value3"},
+				{"message":"prefix: new err 2","key2":"value2","key3":"value3"}
+			]
+		}`, buf.String())
+	})
+}
+
+func TestPath(t *testing.T) {
+	t.Parallel()
+	err := errors.Wrap("prefix1", errors.Join(
+		errors.New("new err 1").Wrap("prefix2").WithField("key1", "value1").E(),
+		stderrors.New("new err 2"),
+	)).E()
+
+	t.Run("object", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger()
+		logger.Log().Object("error", zerologerrors.Object(err, zerologerrors.WithPath())).Msg("failed")
+		require.JSONEq(t, `{"message":"failed","error":{
+			"message":"new err 1",
+			"path":["prefix1","prefix2"],
+			"key1":"value1",
+			"leaves":[
+				{"message":"new err 1","path":["prefix1","prefix2"],"key1":"value1"},
+				{"message":"new err 2","path":["prefix1"]}
+			]
+		}}`, buf.String())
+	})
+
+	t.Run("top level", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger()
+		zerologerrors.Err(logger.Log(), err, zerologerrors.WithPath()).Msg("failed")
+		require.JSONEq(t, `{
+			"message":"failed",
+			"error":"new err 1",
+			"error.path":["prefix1","prefix2"],
+			"key1":"value1",
+			"leaves":[
+				{"message":"new err 1","path":["prefix1","prefix2"],"key1":"value1"},
+				{"message":"new err 2","path":["prefix1"]}
+			]
+		}`, buf.String())
+	})
+}
+
+func TestGroups(t *testing.T) {
+	t.Parallel()
+	err := errors.New("new err").
+		WithGroup("db", errors.Field{Key: "table", Value: "orders"}, errors.Group("query",
+			errors.Field{Key: "id", Value: 1},
+		)).
+		E()
+
+	t.Run("nested", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger()
+		zerologerrors.Err(logger.Log(), err).Msg("failed")
+		require.Equal(t,
+			`{"error":"new err","db":{"table":"orders","query":{"id":1}},"message":"failed"}`+"\n",
+			buf.String(),
+		)
+	})
+
+	t.Run("flatten", func(t *testing.T) {
+		t.Parallel()
+		logger, buf := newLogger()
+		logger.Log().Object("error", zerologerrors.Object(err, zerologerrors.WithGroupSeparator("."))).Msg("failed")
+		require.JSONEq(t,
+			`{"message":"failed","error":{"message":"new err","db.table":"orders","db.query.id":1}}`,
+			buf.String(),
+		)
+	})
+}
+
+func TestNormalizedValues(t *testing.T) {
+	t.Parallel()
+	err := errors.New("new err").
+		With("cause", errors.New("inner").WithField("id", 1).E(), "raw", []byte("bytes"), "ids", []uint{1, 2}).
+		E()
+	logger, buf := newLogger()
+	logger.Log().Object("error", zerologerrors.Object(err)).Msg("failed")
+	require.JSONEq(t,
+		`{"message":"failed","error":{
+			"message":"new err",
+			"cause":{"message":"inner","id":1},
+			"raw":"bytes",
+			"ids":[1,2]
+		}}`,
+		buf.String(),
+	)
+}
+
+func joined() error {
+	return errors.Wrap("prefix", errors.Join(
+		errors.New("new err 1").WithField("key1", "value1").E(),
+		errors.New("new err 2").WithField("key2", "value2").E(),
+	)).WithField("key3", "value3").E()
+}
+
+func newLogger() (zerolog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	return zerolog.New(&buf), &buf
+}
+
+//nolint:paralleltest // modifies global zerolog.ErrorStackMarshaler
+func TestMarshalStack(t *testing.T) {
+	original := zerolog.ErrorStackMarshaler
+	zerolog.ErrorStackMarshaler = zerologerrors.MarshalStack
+	t.Cleanup(func() {
+		zerolog.ErrorStackMarshaler = original
+	})
+
+	t.Run("without stack", func(t *testing.T) {
+		logger, buf := newLogger()
+		logger.Log().Stack().Err(stderrors.New("new err")).Msg("failed")
+		require.JSONEq(t, `{"message":"failed","error":"new err"}`, buf.String())
+	})
+
+	t.Run("with stack", func(t *testing.T) {
+		err, line := errors.New("new err").WithStack().E(), currentLine()
+		logger, buf := newLogger()
+		logger.Log().Stack().Err(err).Msg("failed")
+
+		var obj struct {
+			Stack []struct {
+				Func string `json:"func"`
+				File string `json:"file"`
+				Line int    `json:"line"`
+			} `json:"stack"`
+		}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &obj))
+		require.NotEmpty(t, obj.Stack)
+		require.Contains(t, obj.Stack[0].Func, "zerologerrors_test.TestMarshalStack")
+		require.Equal(t, line, obj.Stack[0].Line)
+		require.True(t, strings.HasSuffix(obj.Stack[0].File, "/zerologerrors_test.go"))
+	})
+}
+
+func currentLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}